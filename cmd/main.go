@@ -2,12 +2,80 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"sshbck/pkg/recorder"
+	"sshbck/pkg/sshclient"
 	"sshbck/pkg/websocket"
+	"strconv"
+	"time"
 )
 
 func main() {
+	resumeGrace, err := time.ParseDuration(getEnv("SSHBCK_RESUME_GRACE", "5m"))
+	if err != nil {
+		log.Fatal("invalid SSHBCK_RESUME_GRACE: ", err)
+	}
+
+	recordMaxSize, err := strconv.ParseInt(getEnv("SSHBCK_RECORD_MAX_SIZE", "0"), 10, 64)
+	if err != nil {
+		log.Fatal("invalid SSHBCK_RECORD_MAX_SIZE: ", err)
+	}
+
+	knownHosts, err := sshclient.NewKnownHosts(getEnv("SSHBCK_KNOWN_HOSTS", "known_hosts"))
+	if err != nil {
+		log.Fatal("known_hosts error: ", err)
+	}
+
+	// 녹화 조회 API와 서버 측 인증 정보 사용에 필요한 토큰
+	apiToken := os.Getenv("SSHBCK_API_TOKEN")
+
+	// SSHBCK_AGENT_SOCKET, SSHBCK_KEYSTORE_DIR은 운영자의 인증 정보로 임의의 호스트에 접속하게 해 주므로
+	// WebSocket 업그레이드 요청에 "Authorization: Bearer <SSHBCK_API_TOKEN>"이 있는 경우에만 사용한다.
+	// /ws는 출처를 확인하지 않으므로 토큰이 노출되면 누구나 이 키로 접속할 수 있다.
+	opts := websocket.Options{
+		AgentSocket:   os.Getenv("SSHBCK_AGENT_SOCKET"), // 운영자의 SSH_AUTH_SOCK은 명시적으로 지정한 경우에만 사용
+		KnownHosts:    knownHosts,
+		HostKeyPolicy: sshclient.HostKeyPolicy(getEnv("SSHBCK_HOSTKEY_POLICY", string(sshclient.HostKeyTOFU))),
+		ResumeGrace:   resumeGrace,
+		Recording: recorder.Config{
+			Dir:         os.Getenv("SSHBCK_RECORD_DIR"),
+			MaxSize:     recordMaxSize,
+			RecordInput: os.Getenv("SSHBCK_RECORD_INPUT") == "true", // 비밀번호 입력도 기록되므로 명시적으로 켠 경우에만
+		},
+		CredentialToken: apiToken,
+	}
+	if dir := os.Getenv("SSHBCK_KEYSTORE_DIR"); dir != "" {
+		opts.KeyStore = sshclient.DirKeyStore{Dir: dir}
+	}
+	if (opts.KeyStore != nil || opts.AgentSocket != "") && apiToken == "" {
+		log.Println("SSHBCK_API_TOKEN is not set, keyRef and useAgent disabled")
+	}
+	websocket.Configure(opts)
+
 	http.HandleFunc("/ws", websocket.HandleWebSocket)
+
+	// 녹화 조회 API (녹화와 API 토큰이 모두 설정된 경우에만)
+	if opts.Recording.Dir != "" && apiToken == "" {
+		log.Println("SSHBCK_API_TOKEN is not set, recordings API disabled")
+	} else if opts.Recording.Dir != "" {
+		recordings := recorder.Handler{
+			Store: recorder.Store{Dir: opts.Recording.Dir},
+			Token: apiToken,
+		}
+		http.HandleFunc("/recordings", recordings.HandleList)
+		http.HandleFunc("/recordings/search", recordings.HandleSearch)
+		http.HandleFunc("/recordings/", recordings.HandlePlayback)
+	}
 	fmt.Println("ssh bridge server started on :8080")
 	http.ListenAndServe(":8080", nil)
 }
+
+// 환경 변수 조회 (없으면 기본값)
+func getEnv(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
@@ -1,30 +1,97 @@
 package queue
 
 import (
-	"container/list"
+	"context"
+	"errors"
+	"sync"
 )
 
+var ErrClosed = errors.New("queue closed")
+
+// 크기가 제한된 바이트 청크 큐 (생산자 하나, 소비자 하나)
+//
+// 큐가 가득 차면 Push가 대기하므로 생산자(SSH 채널 읽기)가 멈추고,
+// 느린 소비자에 맞춰 원격 쪽으로 backpressure가 전달된다.
 type Queue struct {
-	v *list.List
+	ch      chan []byte
+	done    chan struct{}
+	once    sync.Once
+	pending []byte // Pop에서 합치지 못하고 남은 청크 (소비자 전용)
 }
 
-func NewQueue() *Queue {
-	return &Queue{list.New()}
+func NewQueue(size int) *Queue {
+	return &Queue{
+		ch:   make(chan []byte, size),
+		done: make(chan struct{}),
+	}
 }
 
-func (q *Queue) Push(v interface{}) {
-	q.v.PushBack(v)
-}
+// 청크 추가 (큐가 가득 차면 공간이 생길 때까지 대기)
+func (q *Queue) Push(ctx context.Context, v []byte) error {
+	select {
+	case <-q.done:
+		return ErrClosed
+	default:
+	}
 
-func (q *Queue) Pop() interface{} {
-	front := q.v.Front()
-	if front == nil {
+	select {
+	case q.ch <- v:
 		return nil
+	case <-q.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
+
+// 청크가 들어올 때까지 대기한 뒤, 이미 쌓여 있는 청크를 max 바이트까지 합쳐서 반환
+// 큐가 닫히면 남은 청크를 모두 반환한 뒤 ErrClosed를 반환
+func (q *Queue) Pop(ctx context.Context, max int) ([]byte, error) {
+	first := q.pending
+	q.pending = nil
+
+	if first == nil {
+		select {
+		case first = <-q.ch:
+		case <-q.done:
+			select {
+			case first = <-q.ch:
+			default:
+				return nil, ErrClosed
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if len(first) >= max {
+		return first, nil
+	}
+
+	frame := make([]byte, len(first), max)
+	copy(frame, first)
+	for {
+		select {
+		case next := <-q.ch:
+			if len(frame)+len(next) > max {
+				q.pending = next
+				return frame, nil
+			}
+			frame = append(frame, next...)
+		default:
+			return frame, nil
+		}
+	}
+}
 
-	return q.v.Remove(front)
+// 큐 닫기 (생산자가 더 이상 Push하지 않을 때 호출)
+func (q *Queue) Close() {
+	q.once.Do(func() {
+		close(q.done)
+	})
 }
 
+// 대기 중인 청크 수
 func (q *Queue) Len() int {
-	return q.v.Len()
+	return len(q.ch)
 }
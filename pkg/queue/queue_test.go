@@ -0,0 +1,356 @@
+package queue
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func push(t *testing.T, q *Queue, chunks ...string) {
+	t.Helper()
+	for _, chunk := range chunks {
+		if err := q.Push(context.Background(), []byte(chunk)); err != nil {
+			t.Fatalf("Push(%q): %v", chunk, err)
+		}
+	}
+}
+
+func pop(t *testing.T, q *Queue, max int) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	frame, err := q.Pop(ctx, max)
+	if err != nil {
+		t.Fatalf("Pop: %v", err)
+	}
+	return string(frame)
+}
+
+func TestPopCoalescesUpToMax(t *testing.T) {
+	q := NewQueue(8)
+	push(t, q, "aaaa", "bbbb", "cccc", "dd")
+
+	if got := pop(t, q, 10); got != "aaaabbbb" {
+		t.Errorf("first Pop = %q, want %q", got, "aaaabbbb")
+	}
+	// 합치지 못한 청크는 다음 Pop의 맨 앞에 온다
+	if got := pop(t, q, 10); got != "ccccdd" {
+		t.Errorf("second Pop = %q, want %q", got, "ccccdd")
+	}
+	if q.Len() != 0 {
+		t.Errorf("Len = %d after draining", q.Len())
+	}
+}
+
+func TestPopExactMax(t *testing.T) {
+	q := NewQueue(8)
+	push(t, q, "aaaa", "bbbb")
+
+	if got := pop(t, q, 8); got != "aaaabbbb" {
+		t.Errorf("Pop = %q, want %q", got, "aaaabbbb")
+	}
+}
+
+func TestPopLargeChunkNotSplit(t *testing.T) {
+	q := NewQueue(8)
+	push(t, q, "0123456789", "x")
+
+	if got := pop(t, q, 4); got != "0123456789" {
+		t.Errorf("Pop = %q, want the whole chunk", got)
+	}
+	if got := pop(t, q, 4); got != "x" {
+		t.Errorf("Pop = %q, want %q", got, "x")
+	}
+}
+
+func TestPendingCarryOverKeepsOrder(t *testing.T) {
+	q := NewQueue(8)
+	push(t, q, "aaa", "bbbbbb")
+
+	if got := pop(t, q, 5); got != "aaa" {
+		t.Fatalf("first Pop = %q, want %q", got, "aaa")
+	}
+	// pending에 남은 청크가 새로 들어온 청크보다 먼저 나와야 함
+	push(t, q, "c")
+	if got := pop(t, q, 5); got != "bbbbbb" {
+		t.Errorf("second Pop = %q, want pending chunk %q", got, "bbbbbb")
+	}
+	if got := pop(t, q, 5); got != "c" {
+		t.Errorf("third Pop = %q, want %q", got, "c")
+	}
+}
+
+func TestDrainAfterClose(t *testing.T) {
+	q := NewQueue(8)
+	push(t, q, "aaaa", "bbbb", "cccc")
+	q.Close()
+
+	if err := q.Push(context.Background(), []byte("late")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Push after Close = %v, want ErrClosed", err)
+	}
+
+	var drained bytes.Buffer
+	for {
+		frame, err := q.Pop(context.Background(), 6)
+		if errors.Is(err, ErrClosed) {
+			break
+		} else if err != nil {
+			t.Fatalf("Pop: %v", err)
+		}
+		drained.Write(frame)
+	}
+	if drained.String() != "aaaabbbbcccc" {
+		t.Errorf("drained %q, want %q", drained.String(), "aaaabbbbcccc")
+	}
+	q.Close() // 두 번 닫아도 안전
+}
+
+func TestPopBlocksUntilPush(t *testing.T) {
+	q := NewQueue(1)
+	result := make(chan string, 1)
+	go func() {
+		frame, _ := q.Pop(context.Background(), 16)
+		result <- string(frame)
+	}()
+
+	select {
+	case got := <-result:
+		t.Fatalf("Pop returned %q from an empty queue", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+	push(t, q, "data")
+	select {
+	case got := <-result:
+		if got != "data" {
+			t.Errorf("Pop = %q, want %q", got, "data")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Pop did not wake up after Push")
+	}
+}
+
+func TestPopContextCanceled(t *testing.T) {
+	q := NewQueue(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := q.Pop(ctx, 16); !errors.Is(err, context.Canceled) {
+		t.Errorf("Pop = %v, want context.Canceled", err)
+	}
+}
+
+// 큐가 가득 차면 Push가 소비자를 기다리는지 확인 (backpressure)
+func TestPushBlocksWhenFull(t *testing.T) {
+	q := NewQueue(2)
+	push(t, q, "a", "b")
+
+	pushed := make(chan error, 1)
+	go func() {
+		pushed <- q.Push(context.Background(), []byte("c"))
+	}()
+
+	select {
+	case err := <-pushed:
+		t.Fatalf("Push on a full queue returned early: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if got := pop(t, q, 1); got != "a" {
+		t.Fatalf("Pop = %q, want %q", got, "a")
+	}
+	select {
+	case err := <-pushed:
+		if err != nil {
+			t.Fatalf("blocked Push: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Push still blocked after Pop made room")
+	}
+	if got := pop(t, q, 16); got != "bc" {
+		t.Errorf("Pop = %q, want %q", got, "bc")
+	}
+}
+
+func TestPushFullContextTimeout(t *testing.T) {
+	q := NewQueue(1)
+	push(t, q, "a")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := q.Push(ctx, []byte("b")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Push on a full queue = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestCloseWakesBlockedPush(t *testing.T) {
+	q := NewQueue(1)
+	push(t, q, "a")
+
+	pushed := make(chan error, 1)
+	go func() {
+		pushed <- q.Push(context.Background(), []byte("b"))
+	}()
+	time.Sleep(20 * time.Millisecond)
+	q.Close()
+
+	select {
+	case err := <-pushed:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("blocked Push after Close = %v, want ErrClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close did not wake blocked Push")
+	}
+}
+
+// 이전 구현 (container/list 큐를 100ms 간격으로 확인하던 방식)
+//
+// 원래 구현에는 잠금이 없어 동시에 사용하면 데이터 경쟁이 있었으므로 비교용으로 mutex만 추가했다.
+const legacyPollInterval = 100 * time.Millisecond
+
+type legacyQueue struct {
+	mu sync.Mutex
+	v  *list.List
+}
+
+func newLegacyQueue() *legacyQueue {
+	return &legacyQueue{v: list.New()}
+}
+
+func (q *legacyQueue) Push(v interface{}) {
+	q.mu.Lock()
+	q.v.PushBack(v)
+	q.mu.Unlock()
+}
+
+func (q *legacyQueue) Pop() interface{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	front := q.v.Front()
+	if front == nil {
+		return nil
+	}
+	return q.v.Remove(front)
+}
+
+func (q *legacyQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.v.Len()
+}
+
+// 이전 소비자 루프: 비어 있으면 일정 시간 대기 후 다시 확인
+func (q *legacyQueue) consume(ctx context.Context, deliver func([]byte)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			if q.Len() > 0 {
+				deliver(q.Pop().([]byte))
+			} else {
+				time.Sleep(legacyPollInterval)
+			}
+		}
+	}
+}
+
+const (
+	benchChunkSize = 1024
+	benchQueueSize = 256
+	benchFrameSize = 32 * 1024
+)
+
+// 청크 하나를 넣고 소비자가 받을 때까지 걸리는 시간 (키 입력 에코 등 대화형 출력)
+func BenchmarkLatency(b *testing.B) {
+	chunk := make([]byte, benchChunkSize)
+
+	b.Run("bounded", func(b *testing.B) {
+		q := NewQueue(benchQueueSize)
+		received := make(chan struct{})
+		go func() {
+			for {
+				if _, err := q.Pop(context.Background(), benchFrameSize); err != nil {
+					return
+				}
+				received <- struct{}{}
+			}
+		}()
+		defer q.Close()
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			q.Push(context.Background(), chunk)
+			<-received
+		}
+	})
+
+	b.Run("polling", func(b *testing.B) {
+		q := newLegacyQueue()
+		received := make(chan struct{})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go q.consume(ctx, func([]byte) { received <- struct{}{} })
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			q.Push(chunk)
+			<-received
+		}
+	})
+}
+
+// 생산자가 쉬지 않고 청크를 넣을 때 소비자가 모두 받을 때까지의 처리량 (대량 출력)
+func BenchmarkThroughput(b *testing.B) {
+	chunk := make([]byte, benchChunkSize)
+
+	b.Run("bounded", func(b *testing.B) {
+		b.SetBytes(benchChunkSize)
+		q := NewQueue(benchQueueSize)
+		done := make(chan int)
+		go func() {
+			total := 0
+			for {
+				frame, err := q.Pop(context.Background(), benchFrameSize)
+				if err != nil {
+					done <- total
+					return
+				}
+				total += len(frame)
+			}
+		}()
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			q.Push(context.Background(), chunk)
+		}
+		q.Close()
+		if total := <-done; total != b.N*benchChunkSize {
+			b.Fatalf("received %d bytes, want %d", total, b.N*benchChunkSize)
+		}
+	})
+
+	b.Run("polling", func(b *testing.B) {
+		b.SetBytes(benchChunkSize)
+		q := newLegacyQueue()
+		want := b.N * benchChunkSize
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		done := make(chan struct{})
+		total := 0
+		go q.consume(ctx, func(data []byte) {
+			if total += len(data); total == want {
+				close(done)
+			}
+		})
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			q.Push(chunk)
+		}
+		<-done
+	})
+}
@@ -0,0 +1,155 @@
+package recorder
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 녹화 조회 HTTP API
+//
+//	GET /recordings?user=&host=&from=&to=        녹화 목록
+//	GET /recordings/{id}[?part=N]                asciicast 재생 파일
+//	GET /recordings/search?q=&user=&host=&limit= 출력 내용 검색
+type Handler struct {
+	Store Store
+	Token string // "Authorization: Bearer <token>" 으로 확인 (비어 있으면 모든 요청 거부)
+}
+
+// 녹화 목록 조회
+func (h Handler) HandleList(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	filter, err := parseFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recordings, err := h.Store.List(filter)
+	if err != nil {
+		log.Println("recording list error:", err)
+		http.Error(w, "recording list error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, recordings)
+}
+
+// 녹화 재생 파일 스트리밍 (/recordings/{id})
+func (h Handler) HandlePlayback(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	meta, err := h.Store.Get(strings.TrimPrefix(r.URL.Path, "/recordings/"))
+	if errors.Is(err, ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		log.Println("recording read error:", err)
+		http.Error(w, "recording read error", http.StatusInternalServerError)
+		return
+	}
+
+	// 특정 파트만 요청하면 해당 파트만 전송
+	if value := r.URL.Query().Get("part"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n >= len(meta.Parts) {
+			http.Error(w, "invalid part: "+value, http.StatusBadRequest)
+			return
+		}
+		meta.Parts = meta.Parts[n : n+1]
+	}
+
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	w.Header().Set("Content-Disposition", `inline; filename="`+meta.ID+`.cast"`)
+	if err := h.Store.WriteCast(w, meta); err != nil {
+		// 이미 응답을 보내기 시작했으므로 기록만 남김
+		log.Println("recording stream error:", err)
+	}
+}
+
+// 녹화 출력 검색
+func (h Handler) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	filter, err := parseFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+			return
+		}
+	}
+	if query.Get("q") == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+
+	matches, err := h.Store.Search(query.Get("q"), filter, limit)
+	if err != nil {
+		log.Println("recording search error:", err)
+		http.Error(w, "recording search error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, matches)
+}
+
+func (h Handler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	if h.Token == "" {
+		// 토큰 없이는 녹화 내용을 공개하지 않음
+		http.Error(w, "recordings API is disabled", http.StatusForbidden)
+		return false
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.Token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// 쿼리 파라미터에서 필터 생성 (from, to는 RFC3339)
+func parseFilter(r *http.Request) (Filter, error) {
+	query := r.URL.Query()
+	filter := Filter{
+		User: query.Get("user"),
+		Host: query.Get("host"),
+	}
+
+	var err error
+	if value := query.Get("from"); value != "" {
+		if filter.From, err = time.Parse(time.RFC3339, value); err != nil {
+			return filter, errors.New("invalid from: " + value)
+		}
+	}
+	if value := query.Get("to"); value != "" {
+		if filter.To, err = time.Parse(time.RFC3339, value); err != nil {
+			return filter, errors.New("invalid to: " + value)
+		}
+	}
+	return filter, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("json encode error:", err)
+	}
+}
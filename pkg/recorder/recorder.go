@@ -0,0 +1,340 @@
+package recorder
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// asciicast v2 이벤트 종류
+const (
+	EventOutput = "o"
+	EventInput  = "i"
+	EventResize = "r"
+)
+
+// 녹화 설정
+type Config struct {
+	Dir         string // 녹화 파일 저장 디렉토리
+	MaxSize     int64  // 파일 하나의 최대 크기 (0이면 분할하지 않음)
+	RecordInput bool   // 입력 이벤트 기록 여부
+}
+
+// 녹화 메타데이터 (<id>.json 으로 저장)
+type Metadata struct {
+	ID      string     `json:"id"`
+	User    string     `json:"user"`
+	Host    string     `json:"host"`
+	Channel string     `json:"channel"`
+	Start   time.Time  `json:"start"`
+	End     *time.Time `json:"end,omitempty"`
+	Parts   []Part     `json:"parts"`
+}
+
+// 크기 제한으로 분할된 녹화 파일
+type Part struct {
+	File  string    `json:"file"`
+	Start time.Time `json:"start"` // 파트 내 이벤트 시간의 기준 시각
+}
+
+// asciicast v2 헤더
+type header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+type event struct {
+	at   time.Time
+	kind string
+	data []byte
+	cols int
+	rows int
+}
+
+// PTY 세션 녹화기
+//
+// Output/Input/Resize는 이벤트를 메모리에 쌓기만 하고 바로 반환하며,
+// 파일 쓰기는 별도 고루틴에서 처리하므로 실시간 출력에 지연을 주지 않는다.
+type Recorder struct {
+	cfg  Config
+	meta Metadata
+
+	mu      sync.Mutex
+	pending []event
+	closed  bool
+	wake    chan struct{}
+	done    chan struct{}
+
+	// 기록 고루틴 전용
+	file      *os.File
+	writer    *bufio.Writer
+	written   int64
+	partStart time.Time
+	width     int
+	height    int
+	tails     map[string][]byte // 청크 경계에서 잘린 UTF-8 바이트 (이벤트 종류별)
+}
+
+// 새 녹화 시작
+func New(cfg Config, user, host, channel string, cols, rows int) (*Recorder, error) {
+	if err := os.MkdirAll(cfg.Dir, 0700); err != nil {
+		return nil, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{
+		cfg: cfg,
+		meta: Metadata{
+			ID:      id,
+			User:    user,
+			Host:    host,
+			Channel: channel,
+			Start:   time.Now().UTC(),
+		},
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		width:  cols,
+		height: rows,
+		tails:  make(map[string][]byte),
+	}
+
+	if err := r.openPart(r.meta.Start); err != nil {
+		return nil, err
+	}
+	go r.run()
+	return r, nil
+}
+
+// 녹화 ID
+func (r *Recorder) ID() string {
+	return r.meta.ID
+}
+
+// 터미널 출력 기록
+func (r *Recorder) Output(data []byte) {
+	r.push(event{kind: EventOutput, data: append([]byte(nil), data...)})
+}
+
+// 터미널 입력 기록
+func (r *Recorder) Input(data []byte) {
+	if !r.cfg.RecordInput {
+		return
+	}
+	r.push(event{kind: EventInput, data: append([]byte(nil), data...)})
+}
+
+// 터미널 크기 변경 기록
+func (r *Recorder) Resize(cols, rows int) {
+	r.push(event{kind: EventResize, cols: cols, rows: rows})
+}
+
+// 남은 이벤트를 모두 기록하고 종료
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.mu.Unlock()
+
+	r.signal()
+	<-r.done
+	return nil
+}
+
+func (r *Recorder) push(ev event) {
+	ev.at = time.Now()
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.pending = append(r.pending, ev)
+	r.mu.Unlock()
+
+	r.signal()
+}
+
+func (r *Recorder) signal() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// 쌓인 이벤트를 파일에 기록
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	for range r.wake {
+		r.mu.Lock()
+		events := r.pending
+		r.pending = nil
+		closed := r.closed
+		r.mu.Unlock()
+
+		for _, ev := range events {
+			if err := r.write(ev); err != nil {
+				log.Println("recording write error:", err)
+			}
+		}
+		if err := r.writer.Flush(); err != nil {
+			log.Println("recording flush error:", err)
+		}
+
+		if closed {
+			r.finish()
+			return
+		}
+	}
+}
+
+func (r *Recorder) write(ev event) error {
+	var data string
+	if ev.kind == EventResize {
+		r.width, r.height = ev.cols, ev.rows
+		data = strconv.Itoa(ev.cols) + "x" + strconv.Itoa(ev.rows)
+	} else {
+		data = r.completeRunes(ev.kind, ev.data)
+		if data == "" {
+			return nil
+		}
+	}
+
+	if r.cfg.MaxSize > 0 && r.written >= r.cfg.MaxSize {
+		if err := r.rotate(ev.at); err != nil {
+			return err
+		}
+	}
+
+	line, err := json.Marshal([]interface{}{ev.at.Sub(r.partStart).Seconds(), ev.kind, data})
+	if err != nil {
+		return err
+	}
+	n, err := r.writer.Write(append(line, '\n'))
+	r.written += int64(n)
+	return err
+}
+
+// 이전 청크에서 잘린 바이트를 이어 붙이고, 끝에서 잘린 UTF-8 문자는 다음 청크로 미룸
+func (r *Recorder) completeRunes(kind string, data []byte) string {
+	buf := append(r.tails[kind], data...)
+
+	cut := len(buf)
+	for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if !utf8.FullRune(buf[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+
+	r.tails[kind] = append([]byte(nil), buf[cut:]...)
+	return string(buf[:cut])
+}
+
+// 현재 파트를 닫고 새 파트 시작
+func (r *Recorder) rotate(at time.Time) error {
+	if err := r.closePart(); err != nil {
+		return err
+	}
+	return r.openPart(at)
+}
+
+func (r *Recorder) openPart(start time.Time) error {
+	name := r.meta.ID + ".cast"
+	if n := len(r.meta.Parts); n > 0 {
+		name = fmt.Sprintf("%s.%d.cast", r.meta.ID, n)
+	}
+
+	file, err := os.OpenFile(filepath.Join(r.cfg.Dir, name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	r.file = file
+	r.writer = bufio.NewWriter(file)
+	r.written = 0
+	r.partStart = start
+	r.meta.Parts = append(r.meta.Parts, Part{File: name, Start: start.UTC()})
+
+	line, err := json.Marshal(header{
+		Version:   2,
+		Width:     r.width,
+		Height:    r.height,
+		Timestamp: start.Unix(),
+		Title:     r.meta.User + "@" + r.meta.Host,
+		Env:       map[string]string{"TERM": "xterm"},
+	})
+	if err != nil {
+		return err
+	}
+	n, err := r.writer.Write(append(line, '\n'))
+	r.written += int64(n)
+	if err != nil {
+		return err
+	}
+	return r.saveMetadata()
+}
+
+func (r *Recorder) closePart() error {
+	if err := r.writer.Flush(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+func (r *Recorder) finish() {
+	if err := r.closePart(); err != nil {
+		log.Println("recording close error:", err)
+	}
+	end := time.Now().UTC()
+	r.meta.End = &end
+	if err := r.saveMetadata(); err != nil {
+		log.Println("recording metadata error:", err)
+	}
+}
+
+// 메타데이터 저장 (임시 파일에 쓴 뒤 교체)
+func (r *Recorder) saveMetadata() error {
+	data, err := json.MarshalIndent(r.meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(r.cfg.Dir, r.meta.ID+".json")
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// 시간순으로 정렬되는 녹화 ID 생성
+func newID() (string, error) {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(buf), nil
+}
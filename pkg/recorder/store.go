@@ -0,0 +1,321 @@
+package recorder
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 녹화 검색 결과 최대 개수 기본값
+const defaultSearchLimit = 100
+
+// 검색 결과 주변 문맥 길이
+const searchContext = 40
+
+var (
+	ErrNotFound = errors.New("recording not found")
+
+	// 검색 전에 제거할 터미널 제어 시퀀스 (CSI, OSC)
+	ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[()][0-9A-Za-z]|\x1b[=>]`)
+)
+
+// 녹화 파일 저장소
+type Store struct {
+	Dir string
+}
+
+// 녹화 목록 필터
+type Filter struct {
+	User string
+	Host string
+	From time.Time // 이 시각 이후까지 진행된 녹화
+	To   time.Time // 이 시각 이전에 시작된 녹화
+}
+
+// 녹화 출력 검색 결과
+type Match struct {
+	ID      string    `json:"id"`
+	User    string    `json:"user"`
+	Host    string    `json:"host"`
+	Time    time.Time `json:"time"`    // 문자열이 출력된 시각
+	Offset  float64   `json:"offset"`  // 녹화 시작부터의 경과 시간 (초)
+	Context string    `json:"context"` // 일치한 부분 주변 출력
+}
+
+// 필터와 일치하는 녹화 목록 (최근 녹화 우선)
+func (s Store) List(filter Filter) ([]Metadata, error) {
+	paths, err := filepath.Glob(filepath.Join(s.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	recordings := []Metadata{}
+	for _, path := range paths {
+		meta, err := readMetadata(path)
+		if err != nil {
+			continue
+		}
+		if filter.match(meta) {
+			recordings = append(recordings, meta)
+		}
+	}
+
+	sort.Slice(recordings, func(i, j int) bool {
+		return recordings[i].Start.After(recordings[j].Start)
+	})
+	return recordings, nil
+}
+
+// 녹화 메타데이터 조회
+func (s Store) Get(id string) (Metadata, error) {
+	if !validID(id) {
+		return Metadata{}, ErrNotFound
+	}
+	meta, err := readMetadata(filepath.Join(s.Dir, id+".json"))
+	if os.IsNotExist(err) {
+		return Metadata{}, ErrNotFound
+	}
+	return meta, err
+}
+
+// 분할된 파트를 하나의 asciicast 스트림으로 합쳐서 기록
+// (이후 파트의 이벤트 시간은 첫 파트 기준으로 보정)
+func (s Store) WriteCast(w io.Writer, meta Metadata) error {
+	for i, part := range meta.Parts {
+		shift := part.Start.Sub(meta.Parts[0].Start).Seconds()
+		err := s.readPart(part, func(line []byte, ev []interface{}) error {
+			if ev == nil {
+				// 헤더는 첫 파트의 것만 사용
+				if i > 0 {
+					return nil
+				}
+			} else if shift > 0 {
+				ev[0] = ev[0].(float64) + shift
+				var err error
+				if line, err = json.Marshal(ev); err != nil {
+					return err
+				}
+			}
+			_, err := w.Write(append(line, '\n'))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 녹화된 출력에서 문자열 검색 (대소문자 구분 없음)
+func (s Store) Search(query string, filter Filter, limit int) ([]Match, error) {
+	if query == "" {
+		return nil, errors.New("query is required")
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+
+	recordings, err := s.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// 소문자로 바꾸면 바이트 길이가 달라지는 문자가 있으므로 원문에서 대소문자 구분 없이 검색
+	pattern := regexp.MustCompile("(?i)" + regexp.QuoteMeta(query))
+	// 대소문자가 다른 문자는 UTF-8 길이가 다를 수 있으므로 한 글자당 최대 길이로 계산
+	keep := utf8.UTFMax*utf8.RuneCountInString(query) - 1
+
+	matches := []Match{}
+	for _, meta := range recordings {
+		found, err := s.searchRecording(meta, pattern, keep, limit-len(matches))
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, found...)
+		if len(matches) >= limit {
+			break
+		}
+	}
+	return matches, nil
+}
+
+func (s Store) searchRecording(meta Metadata, pattern *regexp.Regexp, keep, limit int) ([]Match, error) {
+	var (
+		matches []Match
+		window  string // 이벤트 경계에 걸친 문자열도 찾기 위해 직전 출력 일부를 유지
+		errStop = errors.New("stop")
+	)
+
+	for _, part := range meta.Parts {
+		err := s.readPart(part, func(line []byte, ev []interface{}) error {
+			if ev == nil || len(ev) < 3 || ev[1] != EventOutput {
+				return nil
+			}
+			text, _ := ev[2].(string)
+			text = ansiPattern.ReplaceAllString(text, "")
+			if text == "" {
+				return nil
+			}
+
+			carry := len(window)
+			window += text
+
+			for start := 0; start < len(window); {
+				loc := pattern.FindStringIndex(window[start:])
+				if loc == nil {
+					break
+				}
+				idx, end := start+loc[0], start+loc[1]
+				// 이전 이벤트 안에서 이미 찾은 결과는 제외
+				if end > carry {
+					at := part.Start.Add(time.Duration(ev[0].(float64) * float64(time.Second)))
+					matches = append(matches, Match{
+						ID:      meta.ID,
+						User:    meta.User,
+						Host:    meta.Host,
+						Time:    at,
+						Offset:  at.Sub(meta.Start).Seconds(),
+						Context: snippet(window, idx, end-idx),
+					})
+					if len(matches) >= limit {
+						return errStop
+					}
+				}
+				_, size := utf8.DecodeRuneInString(window[idx:])
+				start = idx + size
+			}
+
+			if len(window) > keep {
+				cut := len(window) - keep
+				// 글자 중간에서 자르지 않음
+				for cut < len(window) && !utf8.RuneStart(window[cut]) {
+					cut++
+				}
+				window = window[cut:]
+			}
+			return nil
+		})
+		if err == errStop {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+// 파트 파일을 한 줄씩 읽어 전달 (헤더 줄은 ev가 nil)
+func (s Store) readPart(part Part, fn func(line []byte, ev []interface{}) error) error {
+	if !validPartName(part.File) {
+		return fmt.Errorf("invalid part name: %s", part.File)
+	}
+	file, err := os.Open(filepath.Join(s.Dir, part.File))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for first := true; ; first = false {
+		line, err := reader.ReadBytes('\n')
+		line = bytes.TrimRight(line, "\n")
+		if len(line) > 0 {
+			var fnErr error
+			if first {
+				fnErr = fn(line, nil)
+			} else if ev, ok := parseEvent(line); ok {
+				fnErr = fn(line, ev)
+			}
+			if fnErr != nil {
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// 이벤트 줄 해석 (기록 중 잘린 줄은 무시)
+func parseEvent(line []byte) ([]interface{}, bool) {
+	var ev []interface{}
+	if err := json.Unmarshal(line, &ev); err != nil || len(ev) < 2 {
+		return nil, false
+	}
+	if _, ok := ev[0].(float64); !ok {
+		return nil, false
+	}
+	return ev, true
+}
+
+func (f Filter) match(meta Metadata) bool {
+	if f.User != "" && f.User != meta.User {
+		return false
+	}
+	if f.Host != "" && !strings.HasPrefix(meta.Host, f.Host) {
+		return false
+	}
+	if !f.To.IsZero() && meta.Start.After(f.To) {
+		return false
+	}
+	if !f.From.IsZero() && meta.End != nil && meta.End.Before(f.From) {
+		return false
+	}
+	return true
+}
+
+func readMetadata(path string) (Metadata, error) {
+	var meta Metadata
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return meta, err
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, err
+	}
+	if len(meta.Parts) == 0 {
+		return meta, errors.New("recording has no parts")
+	}
+	return meta, nil
+}
+
+// 일치한 부분 주변 문자열
+func snippet(text string, idx, n int) string {
+	start := idx - searchContext
+	if start < 0 {
+		start = 0
+	}
+	end := idx + n + searchContext
+	if end > len(text) {
+		end = len(text)
+	}
+	if start > end {
+		start = end
+	}
+	return strings.ToValidUTF8(text[start:end], "")
+}
+
+var (
+	idPattern   = regexp.MustCompile(`^[0-9TZ]+-[0-9a-f]+$`)
+	partPattern = regexp.MustCompile(`^[0-9TZ]+-[0-9a-f]+(\.[0-9]+)?\.cast$`)
+)
+
+func validID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+func validPartName(name string) bool {
+	return partPattern.MatchString(name)
+}
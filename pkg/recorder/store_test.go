@@ -0,0 +1,88 @@
+package recorder
+
+import (
+	"strings"
+	"testing"
+)
+
+// 출력 청크를 하나씩 출력 이벤트로 녹화한 저장소
+func record(t *testing.T, chunks ...string) Store {
+	t.Helper()
+	dir := t.TempDir()
+	r, err := New(Config{Dir: dir}, "user", "host", "default", 80, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range chunks {
+		r.Output([]byte(chunk))
+	}
+	r.Close()
+	return Store{Dir: dir}
+}
+
+func search(t *testing.T, store Store, query string) []Match {
+	t.Helper()
+	matches, err := store.Search(query, Filter{}, 0)
+	if err != nil {
+		t.Fatalf("Search(%q): %v", query, err)
+	}
+	return matches
+}
+
+func TestSearchCaseInsensitive(t *testing.T) {
+	store := record(t, "Permission DENIED\r\n", "\x1b[31mdenied\x1b[0m\r\n")
+
+	matches := search(t, store, "Denied")
+	if len(matches) != 2 {
+		t.Fatalf("matches = %+v, want 2", matches)
+	}
+	if !strings.Contains(matches[0].Context, "Permission DENIED") {
+		t.Errorf("context = %q", matches[0].Context)
+	}
+}
+
+// 이벤트 경계에 걸친 문자열도 한 번만 찾음
+func TestSearchAcrossEvents(t *testing.T) {
+	store := record(t, "conn", "ection ref", "used\r\n")
+
+	matches := search(t, store, "connection refused")
+	if len(matches) != 1 || !strings.Contains(matches[0].Context, "connection refused") {
+		t.Errorf("matches = %+v, want one match", matches)
+	}
+}
+
+// 소문자로 바꾸면 UTF-8 길이가 달라지는 문자 (İ 2→1, ẞ 3→2, Ⱥ 2→3 바이트)
+func TestSearchNonASCII(t *testing.T) {
+	store := record(t,
+		strings.Repeat("İ", 50)+" İstanbul hata: dosya bulunamadı\r\n",
+		strings.Repeat("ẞ", 50)+" STRAẞE "+strings.Repeat("Ⱥ", 50)+" Fehler\r\n",
+		"ⱥⱥ 한글 출력 ",
+		"오류 발생\r\n",
+	)
+
+	tests := []struct {
+		query   string
+		count   int
+		context string
+	}{
+		{"hata", 1, "İstanbul hata: dosya"},
+		{"İSTANBUL", 1, "İstanbul"},
+		{"straße", 1, "STRAẞE"},
+		{"fehler", 1, "ȺȺȺ Fehler"},
+		{"E ⱥⱥⱥ", 1, "STRAẞE ȺȺȺ"},
+		{"ⱥ", 52, ""},
+		{"출력 오류", 1, "한글 출력 오류 발생"},
+	}
+	for _, tt := range tests {
+		matches := search(t, store, tt.query)
+		if len(matches) != tt.count {
+			t.Errorf("Search(%q) found %d, want %d: %+v", tt.query, len(matches), tt.count, matches)
+			continue
+		}
+		for _, m := range matches {
+			if !strings.Contains(m.Context, tt.context) {
+				t.Errorf("Search(%q) context = %q, want %q", tt.query, m.Context, tt.context)
+			}
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package sshclient
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// 디렉토리 압축 형식
+type ArchiveFormat string
+
+const (
+	ArchiveTarGz ArchiveFormat = "tar.gz"
+	ArchiveZip   ArchiveFormat = "zip"
+)
+
+var ErrArchiveTooLarge = errors.New("archive exceeds size limit")
+
+// 디렉토리 압축 옵션
+type ArchiveOptions struct {
+	Include []string // 비어 있지 않으면 일치하는 파일만 포함 (glob, 상대 경로 또는 파일 이름)
+	Exclude []string // 일치하는 파일과 디렉토리 제외 (glob, 상대 경로 또는 파일 이름)
+	MaxSize int64    // 원본 파일 크기 합계 제한 (0이면 제한 없음)
+
+	// 파일을 추가할 때마다 호출 (누적 파일 수와 원본 크기)
+	Progress func(name string, files int, bytes int64)
+}
+
+// 압축 형식별 항목 기록기
+type archiveWriter interface {
+	add(name string, info os.FileInfo, link string, content io.Reader) error
+	Close() error
+}
+
+// 원격 디렉토리를 압축하여 w에 기록 (압축 파일 안의 경로는 디렉토리 이름부터 시작)
+func (sshCtx *SSHContext) WriteArchive(ctx context.Context, root string, format ArchiveFormat, opts ArchiveOptions, w io.Writer) error {
+	root = path.Clean(root)
+	info, err := sshCtx.SFTPClient.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New("not a directory: " + root)
+	}
+
+	var aw archiveWriter
+	switch format {
+	case ArchiveTarGz:
+		aw = newTarGzWriter(w)
+	case ArchiveZip:
+		aw = &zipWriter{zw: zip.NewWriter(w)}
+	default:
+		return errors.New("unsupported archive format: " + string(format))
+	}
+
+	base := ArchiveBaseName(root)
+	files, total := 0, int64(0)
+	walker := sshCtx.SFTPClient.Walk(root)
+	for walker.Step() {
+		if err := ctx.Err(); err != nil {
+			aw.Close()
+			return err
+		}
+		if err := walker.Err(); err != nil {
+			aw.Close()
+			return err
+		}
+
+		p, info := walker.Path(), walker.Stat()
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+		name := path.Join(base, rel)
+
+		if rel != "" && matchAny(opts.Exclude, rel) {
+			if info.IsDir() {
+				walker.SkipDir()
+			}
+			continue
+		}
+
+		var err error
+		switch {
+		case info.IsDir():
+			err = aw.add(name+"/", info, "", nil)
+		case len(opts.Include) > 0 && !matchAny(opts.Include, rel):
+			continue
+		case info.Mode()&os.ModeSymlink != 0:
+			var link string
+			if link, err = sshCtx.SFTPClient.ReadLink(p); err == nil {
+				err = aw.add(name, info, link, nil)
+			}
+		case info.Mode().IsRegular():
+			if total += info.Size(); opts.MaxSize > 0 && total > opts.MaxSize {
+				aw.Close()
+				return ErrArchiveTooLarge
+			}
+			err = sshCtx.addArchiveFile(aw, p, name, info)
+			files++
+		default:
+			// 소켓, 장치 파일 등은 건너뜀
+			continue
+		}
+		if err != nil {
+			aw.Close()
+			return fmt.Errorf("archive %s: %w", p, err)
+		}
+		if opts.Progress != nil && !info.IsDir() {
+			opts.Progress(name, files, total)
+		}
+	}
+	return aw.Close()
+}
+
+// 압축 파일 안의 최상위 디렉토리 이름 (루트 디렉토리는 절대 경로가 되지 않도록 "root")
+func ArchiveBaseName(root string) string {
+	base := path.Base(path.Clean(root))
+	if base == "/" || base == "." || base == ".." {
+		return "root"
+	}
+	return base
+}
+
+func (sshCtx *SSHContext) addArchiveFile(aw archiveWriter, p, name string, info os.FileInfo) error {
+	file, err := sshCtx.SFTPClient.Open(p)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	// 읽는 동안 파일이 커져도 헤더의 크기만큼만 기록
+	return aw.add(name, info, "", io.LimitReader(file, info.Size()))
+}
+
+// 상대 경로 또는 파일 이름이 패턴 중 하나와 일치하는지 확인
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type tarGzWriter struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+func newTarGzWriter(w io.Writer) *tarGzWriter {
+	gz := gzip.NewWriter(w)
+	return &tarGzWriter{gz: gz, tw: tar.NewWriter(gz)}
+}
+
+func (t *tarGzWriter) add(name string, info os.FileInfo, link string, content io.Reader) error {
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if err := t.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if content != nil {
+		if _, err := io.Copy(t.tw, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *tarGzWriter) Close() error {
+	if err := t.tw.Close(); err != nil {
+		t.gz.Close()
+		return err
+	}
+	return t.gz.Close()
+}
+
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+func (z *zipWriter) add(name string, info os.FileInfo, link string, content io.Reader) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if !info.IsDir() {
+		header.Method = zip.Deflate
+	}
+
+	w, err := z.zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	if link != "" {
+		// zip은 심볼릭 링크 대상을 파일 내용으로 저장
+		_, err = io.WriteString(w, link)
+		return err
+	}
+	if content != nil {
+		_, err = io.Copy(w, content)
+	}
+	return err
+}
+
+func (z *zipWriter) Close() error {
+	return z.zw.Close()
+}
@@ -0,0 +1,218 @@
+package sshclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// 인증 방식
+type AuthType string
+
+const (
+	AuthPassword    AuthType = "password"
+	AuthPrivateKey  AuthType = "privatekey"
+	AuthCertificate AuthType = "certificate"
+	AuthKeyStore    AuthType = "keystore"
+	AuthAgent       AuthType = "agent"
+
+	AuthKeyboardInteractive AuthType = "keyboard-interactive"
+)
+
+// 인증 순서를 지정하지 않았을 때 사용하는 기본 순서
+var DefaultAuthOrder = []AuthType{
+	AuthCertificate,
+	AuthPrivateKey,
+	AuthKeyStore,
+	AuthAgent,
+	AuthPassword,
+	AuthKeyboardInteractive,
+}
+
+// 서버 측에 보관된 개인키 저장소
+type KeyStore interface {
+	Load(name string) ([]byte, error)
+}
+
+// 디렉토리 기반 개인키 저장소 (<Dir>/<name> 파일을 읽음)
+type DirKeyStore struct {
+	Dir string
+}
+
+// SSH 인증 설정
+type AuthConfig struct {
+	User        string
+	Password    string
+	PrivateKey  []byte // PEM 형식 개인키
+	Passphrase  string // 개인키 암호
+	Certificate []byte // OpenSSH 인증서 (authorized_keys 형식)
+	KeyRef      string // KeyStore에 저장된 키 이름
+	KeyStore    KeyStore
+	AgentSocket string // ssh-agent 소켓 경로 (비어있으면 사용하지 않음)
+	Order       []AuthType
+
+	// keyboard-interactive(OTP 등) 질의 응답 함수
+	KeyboardInteractive ssh.KeyboardInteractiveChallenge
+}
+
+// 저장소에서 키 읽기
+func (s DirKeyStore) Load(name string) ([]byte, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid key name: %q", name)
+	}
+	return os.ReadFile(filepath.Join(s.Dir, name))
+}
+
+// 설정된 순서대로 ssh.AuthMethod 목록 생성
+//
+// ssh 패키지는 같은 종류의 인증 방식을 한 번만 시도하므로,
+// 공개키 계열(개인키, 인증서, 키 저장소, agent)의 signer는 순서를 유지한 채
+// 첫 번째 공개키 계열 위치에 하나의 PublicKeys 인증으로 묶는다.
+// 반환된 io.Closer는 연결이 끝난 뒤 닫아야 한다.
+func (a *AuthConfig) Methods() (methods []ssh.AuthMethod, closer io.Closer, err error) {
+	order := a.Order
+	if len(order) == 0 {
+		order = DefaultAuthOrder
+	}
+
+	var (
+		signers   []ssh.Signer
+		keyIndex  = -1
+		agentConn net.Conn
+	)
+	defer func() {
+		if err != nil && agentConn != nil {
+			agentConn.Close()
+		}
+	}()
+
+	for _, authType := range order {
+		switch authType {
+		case AuthPassword:
+			if a.Password != "" {
+				methods = append(methods, ssh.Password(a.Password))
+			}
+			continue
+		case AuthKeyboardInteractive:
+			if a.KeyboardInteractive != nil {
+				methods = append(methods, ssh.KeyboardInteractive(a.KeyboardInteractive))
+			}
+			continue
+		case AuthPrivateKey:
+			if len(a.PrivateKey) == 0 {
+				continue
+			}
+			signer, err := parseSigner(a.PrivateKey, a.Passphrase)
+			if err != nil {
+				return nil, nil, fmt.Errorf("private key: %v", err)
+			}
+			signers = append(signers, signer)
+		case AuthCertificate:
+			if len(a.Certificate) == 0 {
+				continue
+			}
+			signer, err := a.certSigner()
+			if err != nil {
+				return nil, nil, fmt.Errorf("certificate: %v", err)
+			}
+			signers = append(signers, signer)
+		case AuthKeyStore:
+			if a.KeyRef == "" {
+				continue
+			}
+			if a.KeyStore == nil {
+				return nil, nil, errors.New("key store is not configured")
+			}
+			pem, err := a.KeyStore.Load(a.KeyRef)
+			if err != nil {
+				return nil, nil, fmt.Errorf("key store: %v", err)
+			}
+			signer, err := parseSigner(pem, a.Passphrase)
+			if err != nil {
+				return nil, nil, fmt.Errorf("key store: %v", err)
+			}
+			signers = append(signers, signer)
+		case AuthAgent:
+			if a.AgentSocket == "" || agentConn != nil {
+				continue
+			}
+			conn, err := net.Dial("unix", a.AgentSocket)
+			if err != nil {
+				return nil, nil, fmt.Errorf("ssh-agent: %v", err)
+			}
+			agentConn = conn
+			agentSigners, err := agent.NewClient(conn).Signers()
+			if err != nil {
+				return nil, nil, fmt.Errorf("ssh-agent: %v", err)
+			}
+			signers = append(signers, agentSigners...)
+		default:
+			return nil, nil, fmt.Errorf("unsupported auth type: %s", authType)
+		}
+
+		if keyIndex < 0 {
+			keyIndex = len(methods)
+			methods = append(methods, nil)
+		}
+	}
+
+	if keyIndex >= 0 {
+		if len(signers) > 0 {
+			methods[keyIndex] = ssh.PublicKeys(signers...)
+		} else {
+			methods = append(methods[:keyIndex], methods[keyIndex+1:]...)
+		}
+	}
+
+	if len(methods) == 0 {
+		return nil, nil, errors.New("no auth method available")
+	}
+
+	return methods, closerOf(agentConn), nil
+}
+
+// 인증서와 개인키를 묶은 signer 생성
+func (a *AuthConfig) certSigner() (ssh.Signer, error) {
+	if len(a.PrivateKey) == 0 {
+		return nil, errors.New("certificate requires a private key")
+	}
+	signer, err := parseSigner(a.PrivateKey, a.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(a.Certificate)
+	if err != nil {
+		return nil, err
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, errors.New("not an OpenSSH certificate")
+	}
+	return ssh.NewCertSigner(cert, signer)
+}
+
+// PEM 개인키 파싱 (암호가 있으면 복호화)
+func parseSigner(pem []byte, passphrase string) (ssh.Signer, error) {
+	if passphrase != "" {
+		return ssh.ParsePrivateKeyWithPassphrase(pem, []byte(passphrase))
+	}
+	return ssh.ParsePrivateKey(pem)
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+func closerOf(conn net.Conn) io.Closer {
+	if conn == nil {
+		return nopCloser{}
+	}
+	return conn
+}
@@ -0,0 +1,61 @@
+package sshclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// 업로드 검증에 사용하는 체크섬 알고리즘
+type ChecksumAlgorithm string
+
+const (
+	ChecksumSHA256     ChecksumAlgorithm = "sha256"
+	ChecksumBLAKE2b256 ChecksumAlgorithm = "blake2b-256"
+	ChecksumBLAKE2b512 ChecksumAlgorithm = "blake2b-512"
+)
+
+// 알고리즘 이름으로 해시 생성 (빈 값은 SHA-256)
+func NewHash(alg ChecksumAlgorithm) (hash.Hash, error) {
+	switch alg {
+	case "", ChecksumSHA256:
+		return sha256.New(), nil
+	case ChecksumBLAKE2b256:
+		return blake2b.New256(nil)
+	case ChecksumBLAKE2b512:
+		return blake2b.New512(nil)
+	default:
+		return nil, errors.New("unsupported checksum algorithm: " + string(alg))
+	}
+}
+
+// 원격 파일의 offset 이후 내용을 SFTP로 읽어 해시에 추가
+func (sshCtx *SSHContext) hashFile(p string, offset int64, w io.Writer) error {
+	file, err := sshCtx.SFTPClient.Open(p)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// 원격 파일 체크섬 계산 (hex)
+func (sshCtx *SSHContext) Checksum(p string, alg ChecksumAlgorithm) (string, error) {
+	h, err := NewHash(alg)
+	if err != nil {
+		return "", err
+	}
+	if err := sshCtx.hashFile(p, 0, h); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,372 @@
+package sshclient
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// 압축 해제 시 이미 있는 파일 처리 방식
+type ExtractPolicy string
+
+const (
+	ExtractOverwrite ExtractPolicy = "overwrite" // 기존 파일을 교체
+	ExtractSkip      ExtractPolicy = "skip"      // 기존 파일을 유지하고 ErrSkipped 알림
+	ExtractFail      ExtractPolicy = "fail"      // 기존 파일을 유지하고 ErrFileExists 알림
+)
+
+var (
+	ErrSkipped    = errors.New("skipped: file already exists")
+	ErrUnsafePath = errors.New("unsafe path in archive")
+)
+
+// 심볼릭 링크 항목에서 읽는 대상 경로 최대 길이
+const maxLinkLength = 4096
+
+// 업로드한 압축 파일 해제 옵션
+type ExtractOptions struct {
+	Checksum    string        // Upload.Algorithm 으로 계산한 hex 체크섬
+	Destination string        // 압축을 풀 디렉토리 (없으면 생성)
+	Format      ArchiveFormat // 빈 값이면 업로드 파일 이름으로 판단
+	Policy      ExtractPolicy // 빈 값이면 ExtractFail
+	MaxSize     int64         // 압축 해제한 크기 합계 제한 (0이면 제한 없음)
+}
+
+// 파일 이름의 확장자로 압축 형식 판단
+func ArchiveFormatOf(name string) (ArchiveFormat, bool) {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return ArchiveZip, true
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return ArchiveTarGz, true
+	}
+	return "", false
+}
+
+// 업로드한 압축 파일을 대상 디렉토리에 풀고 임시 파일 삭제 (항목마다 notify 호출)
+//
+// 대상 디렉토리를 벗어나는 경로와 링크, 대상 디렉토리 안의 심볼릭 링크를 거치는 경로는
+// ErrUnsafePath로 알리고 건너뛴다. 항목별 오류는 notify로만 알리고 나머지는 계속 진행한다.
+func (sshCtx *SSHContext) ExtractUpload(ctx context.Context, id string, opts ExtractOptions, notify ItemFunc) error {
+	upload, err := sshCtx.Upload(id)
+	if err != nil {
+		return err
+	}
+
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	if err := sshCtx.sealUpload(upload, opts.Checksum, false); err != nil {
+		return err
+	}
+	format := opts.Format
+	if format == "" {
+		var ok bool
+		if format, ok = ArchiveFormatOf(upload.Path); !ok {
+			return sshCtx.reopen(upload, errors.New("unknown archive format: "+path.Base(upload.Path)))
+		}
+	}
+	if format != ArchiveTarGz && format != ArchiveZip {
+		return sshCtx.reopen(upload, errors.New("unsupported archive format: "+string(format)))
+	}
+
+	// 압축 해제를 시작하면 결과와 관계없이 업로드는 끝남
+	defer func() {
+		sshCtx.removeUpload(id)
+		if err := sshCtx.SFTPClient.Remove(upload.tmpPath); err != nil {
+			log.Println("failed to remove staging file:", err)
+		}
+	}()
+
+	file, err := sshCtx.SFTPClient.Open(upload.tmpPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	dest := path.Clean(opts.Destination)
+	if err := sshCtx.SFTPClient.MkdirAll(dest); err != nil {
+		return errors.New("failed to create destination: " + err.Error())
+	}
+	x := &extractor{
+		sshCtx:  sshCtx,
+		dest:    dest,
+		policy:  opts.Policy,
+		maxSize: opts.MaxSize,
+		notify:  notify,
+		dirs:    map[string]bool{dest: true},
+	}
+
+	if format == ArchiveZip {
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		err = x.extractZip(ctx, file, info.Size())
+	} else {
+		err = x.extractTarGz(ctx, file)
+	}
+	x.applyDirModes()
+	return err
+}
+
+type extractor struct {
+	sshCtx  *SSHContext
+	dest    string
+	policy  ExtractPolicy
+	maxSize int64
+	total   int64
+	notify  ItemFunc
+
+	dirs     map[string]bool // 확인했거나 생성한 실제 디렉토리 (심볼릭 링크 아님)
+	dirModes []dirMode       // 하위 항목을 모두 쓴 뒤 적용할 디렉토리 권한
+}
+
+type dirMode struct {
+	path string
+	mode os.FileMode
+}
+
+func (x *extractor) extractTarGz(ctx context.Context, r io.Reader) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return errors.New("invalid tar.gz: " + err.Error())
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.New("invalid tar.gz: " + err.Error())
+		}
+
+		switch header.Typeflag {
+		case tar.TypeXGlobalHeader:
+			continue
+		case tar.TypeLink:
+			x.notify(header.Name, "", errors.New("hard links are not supported"))
+			continue
+		}
+		if err := x.entry(header.Name, header.FileInfo().Mode(), header.ModTime, header.Linkname, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func (x *extractor) extractZip(ctx context.Context, r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return errors.New("invalid zip: " + err.Error())
+	}
+
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := x.zipEntry(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *extractor) zipEntry(f *zip.File) error {
+	mode := f.Mode()
+	if mode.IsDir() {
+		return x.entry(f.Name, mode, f.Modified, "", nil)
+	}
+
+	content, err := f.Open()
+	if err != nil {
+		x.notify(f.Name, "", err)
+		return nil
+	}
+	defer content.Close()
+
+	var link string
+	if mode&os.ModeSymlink != 0 {
+		// zip은 심볼릭 링크 대상을 파일 내용으로 저장
+		buf, err := io.ReadAll(io.LimitReader(content, maxLinkLength))
+		if err != nil {
+			x.notify(f.Name, "", err)
+			return nil
+		}
+		link = string(buf)
+	}
+	return x.entry(f.Name, mode, f.Modified, link, content)
+}
+
+// 항목 하나를 대상 디렉토리에 기록 (압축 해제를 중단해야 하는 경우에만 오류 반환)
+func (x *extractor) entry(name string, mode os.FileMode, modTime time.Time, link string, content io.Reader) error {
+	rel, ok := entryPath(name)
+	if !ok {
+		x.notify(name, "", ErrUnsafePath)
+		return nil
+	}
+	target := path.Join(x.dest, rel)
+	if target == x.dest && mode.IsDir() {
+		// "./" 항목은 대상 디렉토리 자체이므로 권한을 바꾸지 않음
+		return nil
+	}
+
+	var err error
+	switch {
+	case mode.IsDir():
+		if err = x.ensureDir(target); err == nil {
+			x.dirModes = append(x.dirModes, dirMode{path: target, mode: mode.Perm()})
+		}
+	case mode&os.ModeSymlink != 0:
+		if !x.linkInside(target, link) {
+			err = ErrUnsafePath
+		} else if err = x.prepare(target); err == nil {
+			err = x.sshCtx.SFTPClient.Symlink(link, target)
+		}
+	case mode.IsRegular():
+		if err = x.prepare(target); err == nil {
+			err = x.writeFile(target, mode.Perm(), modTime, content)
+		}
+		if errors.Is(err, ErrArchiveTooLarge) {
+			x.notify(name, target, err)
+			return err
+		}
+	default:
+		err = errors.New("unsupported file type: " + mode.Type().String())
+	}
+	x.notify(name, target, err)
+	return nil
+}
+
+// 압축 파일 안의 경로를 대상 디렉토리 기준 상대 경로로 변환 (절대 경로나 상위 디렉토리 참조는 거부)
+func entryPath(name string) (string, bool) {
+	if strings.HasPrefix(name, "/") || strings.Contains(name, "\\") {
+		return "", false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", false
+		}
+	}
+	return path.Clean(name), true
+}
+
+// 링크 대상이 대상 디렉토리 안에 있는지 확인
+func (x *extractor) linkInside(target, link string) bool {
+	if link == "" || path.IsAbs(link) {
+		return false
+	}
+	resolved := path.Join(path.Dir(target), link)
+	return resolved == x.dest || isSubPath(x.dest, resolved)
+}
+
+// 디렉토리가 실제 디렉토리인지 확인하고 없으면 생성 (상위 디렉토리 포함)
+func (x *extractor) ensureDir(dir string) error {
+	if x.dirs[dir] {
+		return nil
+	}
+	if !isSubPath(x.dest, dir) {
+		return ErrUnsafePath
+	}
+	if err := x.ensureDir(path.Dir(dir)); err != nil {
+		return err
+	}
+
+	info, err := x.sshCtx.SFTPClient.Lstat(dir)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if err := x.sshCtx.SFTPClient.Mkdir(dir); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case info.Mode()&os.ModeSymlink != 0:
+		// 링크를 따라가면 대상 디렉토리 밖에 쓸 수 있음
+		return ErrUnsafePath
+	case !info.IsDir():
+		return errors.New("not a directory: " + dir)
+	}
+	x.dirs[dir] = true
+	return nil
+}
+
+// 상위 디렉토리를 준비하고 기존 파일은 정책에 따라 삭제
+func (x *extractor) prepare(target string) error {
+	if err := x.ensureDir(path.Dir(target)); err != nil {
+		return err
+	}
+
+	info, err := x.sshCtx.SFTPClient.Lstat(target)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return errors.New("is a directory: " + target)
+	}
+
+	switch x.policy {
+	case ExtractOverwrite:
+		// 기존 링크나 하드 링크를 따라 쓰지 않도록 삭제 후 새로 생성
+		return x.sshCtx.SFTPClient.Remove(target)
+	case ExtractSkip:
+		return ErrSkipped
+	default:
+		return ErrFileExists
+	}
+}
+
+func (x *extractor) writeFile(target string, perm os.FileMode, modTime time.Time, content io.Reader) error {
+	file, err := x.sshCtx.SFTPClient.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+	if err != nil {
+		return err
+	}
+
+	if x.maxSize > 0 {
+		content = io.LimitReader(content, x.maxSize-x.total+1)
+	}
+	n, err := io.Copy(file, content)
+	x.total += n
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && x.maxSize > 0 && x.total > x.maxSize {
+		err = ErrArchiveTooLarge
+	}
+	if err != nil {
+		x.sshCtx.SFTPClient.Remove(target)
+		return err
+	}
+
+	if err := x.sshCtx.SFTPClient.Chmod(target, perm); err != nil {
+		return err
+	}
+	if !modTime.IsZero() {
+		return x.sshCtx.SFTPClient.Chtimes(target, modTime, modTime)
+	}
+	return nil
+}
+
+// 읽기 전용 디렉토리에도 하위 항목을 쓸 수 있도록 디렉토리 권한은 마지막에 하위부터 적용
+func (x *extractor) applyDirModes() {
+	for i := len(x.dirModes) - 1; i >= 0; i-- {
+		d := x.dirModes[i]
+		if err := x.sshCtx.SFTPClient.Chmod(d.path, d.mode); err != nil {
+			log.Printf("failed to set mode of %s: %v", d.path, err)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package sshclient
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 테스트 압축 파일 항목 (link가 있으면 심볼릭 링크, 이름이 /로 끝나면 디렉토리)
+type archiveEntry struct {
+	name string
+	link string
+	body string
+}
+
+func buildTarGz(t *testing.T, entries []archiveEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(e.body))}
+		switch {
+		case e.link != "":
+			header.Typeflag, header.Linkname, header.Size = tar.TypeSymlink, e.link, 0
+		case e.name[len(e.name)-1] == '/':
+			header.Typeflag, header.Mode = tar.TypeDir, 0755
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func buildZip(t *testing.T, entries []archiveEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		body := e.body
+		switch {
+		case e.link != "":
+			// zip은 심볼릭 링크 대상을 파일 내용으로 저장
+			header.SetMode(os.ModeSymlink | 0777)
+			body = e.link
+		case e.name[len(e.name)-1] == '/':
+			header.SetMode(os.ModeDir | 0755)
+		default:
+			header.SetMode(0644)
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// 압축 파일을 업로드한 뒤 dest에 풀고 항목별 결과 반환
+func extractArchive(t *testing.T, sshCtx *SSHContext, name string, data []byte, dest string) map[string]error {
+	t.Helper()
+	upload, err := sshCtx.StartUpload(filepath.Join(t.TempDir(), name), int64(len(data)), ChecksumSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := upload.WriteAt(0, data); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+
+	results := make(map[string]error)
+	err = sshCtx.ExtractUpload(context.Background(), upload.ID, ExtractOptions{
+		Checksum:    hex.EncodeToString(sum[:]),
+		Destination: dest,
+	}, func(src, dst string, err error) {
+		results[src] = err
+	})
+	if err != nil {
+		t.Fatalf("ExtractUpload(%s): %v", name, err)
+	}
+	return results
+}
+
+func TestExtractUnsafeEntries(t *testing.T) {
+	base := t.TempDir()
+	outside := filepath.Join(base, "outside")
+	if err := os.Mkdir(outside, 0755); err != nil {
+		t.Fatal(err)
+	}
+	sshCtx := newTestContext(t, t.TempDir())
+
+	entries := []archiveEntry{
+		{name: "good", body: "good"},
+		{name: "../escaped", body: "evil"},
+		{name: "dir/../../escaped", body: "evil"},
+		{name: filepath.Join(outside, "absolute"), body: "evil"},
+		{name: "up", link: "../outside"},
+		{name: "root", link: "/"},
+		{name: "abs", link: outside},
+		// 대상 디렉토리 안을 가리키는 링크는 만들지만 그 링크를 거쳐 쓰지 않음
+		{name: "self", link: "."},
+		{name: "self/through-self", body: "evil"},
+		{name: "sub/"},
+		{name: "sub/parent", link: ".."},
+		{name: "sub/parent/through-parent", body: "evil"},
+		{name: "sub/parent/sub/nested/", body: ""},
+		// 미리 있던 링크도 따라가지 않음
+		{name: "existing/through-existing", body: "evil"},
+	}
+	unsafe := []string{
+		"../escaped", "dir/../../escaped", filepath.Join(outside, "absolute"),
+		"up", "root", "abs",
+		"self/through-self", "sub/parent/through-parent", "sub/parent/sub/nested/",
+		"existing/through-existing",
+	}
+	safe := []string{"good", "self", "sub/", "sub/parent"}
+
+	archives := []struct {
+		name string
+		data []byte
+	}{
+		{"unsafe.tar.gz", buildTarGz(t, entries)},
+		{"unsafe.zip", buildZip(t, entries)},
+	}
+	for _, archive := range archives {
+		t.Run(archive.name, func(t *testing.T) {
+			dest := filepath.Join(base, archive.name)
+			if err := os.Mkdir(dest, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Symlink(outside, filepath.Join(dest, "existing")); err != nil {
+				t.Fatal(err)
+			}
+
+			results := extractArchive(t, sshCtx, archive.name, archive.data, dest)
+			for _, name := range unsafe {
+				if err, ok := results[name]; !ok || !errors.Is(err, ErrUnsafePath) {
+					t.Errorf("%q: err = %v, want ErrUnsafePath", name, err)
+				}
+			}
+			for _, name := range safe {
+				if err, ok := results[name]; !ok || err != nil {
+					t.Errorf("%q: err = %v, reported = %v", name, err, ok)
+				}
+			}
+			if readFile(t, filepath.Join(dest, "good")) != "good" {
+				t.Error("safe entry not extracted")
+			}
+
+			if written := listTree(t, outside); len(written) != 0 {
+				t.Errorf("entries written outside destination: %v", written)
+			}
+			for p := range listTree(t, dest) {
+				if info, err := os.Lstat(filepath.Join(dest, p)); err == nil && info.Mode().IsRegular() && p != "good" {
+					t.Errorf("unexpected file extracted: %s", p)
+				}
+			}
+		})
+	}
+
+	// 대상 디렉토리의 상위에도 아무것도 생기지 않음
+	for _, name := range []string{"escaped", "absolute"} {
+		if _, err := os.Lstat(filepath.Join(base, name)); err == nil {
+			t.Errorf("%s created above destination", name)
+		}
+	}
+}
+
+func TestEntryPath(t *testing.T) {
+	tests := []struct {
+		name string
+		rel  string
+		ok   bool
+	}{
+		{"file", "file", true},
+		{"./dir/file", "dir/file", true},
+		{"dir//file", "dir/file", true},
+		{"./", ".", true},
+		{"..", "", false},
+		{"../file", "", false},
+		{"dir/../file", "", false},
+		{"dir/..", "", false},
+		{"/etc/passwd", "", false},
+		{`..\file`, "", false},
+		{`dir\file`, "", false},
+	}
+	for _, tt := range tests {
+		rel, ok := entryPath(tt.name)
+		if rel != tt.rel || ok != tt.ok {
+			t.Errorf("entryPath(%q) = %q, %v, want %q, %v", tt.name, rel, ok, tt.rel, tt.ok)
+		}
+	}
+}
+
+func TestLinkInside(t *testing.T) {
+	x := &extractor{dest: "/dest"}
+	tests := []struct {
+		target string
+		link   string
+		ok     bool
+	}{
+		{"/dest/a", "b", true},
+		{"/dest/a", ".", true},
+		{"/dest/dir/a", "..", true},
+		{"/dest/dir/a", "../b", true},
+		{"/dest/a", "..", false},
+		{"/dest/a", "../dest2", false},
+		{"/dest/dir/a", "../../etc", false},
+		{"/dest/a", "/dest/b", false},
+		{"/dest/a", "", false},
+	}
+	for _, tt := range tests {
+		if ok := x.linkInside(tt.target, tt.link); ok != tt.ok {
+			t.Errorf("linkInside(%q, %q) = %v, want %v", tt.target, tt.link, ok, tt.ok)
+		}
+	}
+}
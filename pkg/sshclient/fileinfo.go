@@ -0,0 +1,123 @@
+package sshclient
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// 파일 종류
+type FileType string
+
+const (
+	FileTypeFile        FileType = "file"
+	FileTypeDir         FileType = "dir"
+	FileTypeSymlink     FileType = "symlink"
+	FileTypeSocket      FileType = "socket"
+	FileTypeFIFO        FileType = "fifo"
+	FileTypeBlockDevice FileType = "block-device"
+	FileTypeCharDevice  FileType = "char-device"
+	FileTypeOther       FileType = "other"
+)
+
+// 시스템 MIME 데이터베이스가 없어도 인식할 텍스트 파일 확장자
+var textMIMETypes = map[string]string{
+	".txt":  "text/plain; charset=utf-8",
+	".log":  "text/plain; charset=utf-8",
+	".conf": "text/plain; charset=utf-8",
+	".cfg":  "text/plain; charset=utf-8",
+	".ini":  "text/plain; charset=utf-8",
+	".toml": "application/toml",
+	".yaml": "application/yaml",
+	".yml":  "application/yaml",
+	".md":   "text/markdown; charset=utf-8",
+	".csv":  "text/csv; charset=utf-8",
+	".sh":   "application/x-sh",
+	".py":   "text/x-python; charset=utf-8",
+	".go":   "text/x-go; charset=utf-8",
+	".gz":   "application/gzip",
+	".tgz":  "application/gzip",
+	".tar":  "application/x-tar",
+	".zip":  "application/zip",
+}
+
+func fileTypeOf(mode os.FileMode) FileType {
+	switch {
+	case mode.IsRegular():
+		return FileTypeFile
+	case mode.IsDir():
+		return FileTypeDir
+	case mode&os.ModeSymlink != 0:
+		return FileTypeSymlink
+	case mode&os.ModeSocket != 0:
+		return FileTypeSocket
+	case mode&os.ModeNamedPipe != 0:
+		return FileTypeFIFO
+	case mode&os.ModeCharDevice != 0:
+		return FileTypeCharDevice
+	case mode&os.ModeDevice != 0:
+		return FileTypeBlockDevice
+	default:
+		return FileTypeOther
+	}
+}
+
+// 특수 권한을 포함한 8진수 권한 (예: "4755")
+func octalMode(mode os.FileMode) string {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 01000
+	}
+	return fmt.Sprintf("%04o", bits)
+}
+
+// 확장자로 MIME 타입 추측 (알 수 없으면 빈 문자열)
+func guessMIME(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if ext == "" {
+		return ""
+	}
+	if t, ok := textMIMETypes[ext]; ok {
+		return t
+	}
+	return mime.TypeByExtension(ext)
+}
+
+// 접근 시각 (SFTP 속성에 없으면 zero)
+func accessTime(file os.FileInfo) time.Time {
+	if stat, ok := file.Sys().(*sftp.FileStat); ok {
+		return time.Unix(int64(stat.Atime), 0)
+	}
+	return time.Time{}
+}
+
+// 심볼릭 링크의 대상 경로와 대상 종류 채우기
+func (sshCtx *SSHContext) resolveLinkInfo(p string, info *FileInfo) {
+	target, err := sshCtx.SFTPClient.ReadLink(p)
+	if err != nil {
+		return
+	}
+	info.LinkTarget = target
+
+	resolved, err := sshCtx.SFTPClient.Stat(p)
+	if err != nil {
+		// 대상이 없거나 링크가 순환하는 경우
+		info.Broken = true
+		return
+	}
+	info.TargetType = fileTypeOf(resolved.Mode())
+	if resolved.Mode().IsRegular() {
+		info.MIME = guessMIME(target)
+	}
+}
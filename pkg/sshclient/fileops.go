@@ -0,0 +1,266 @@
+package sshclient
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/sftp"
+)
+
+var (
+	ErrFileExists = errors.New("file already exists")
+	ErrIntoItself = errors.New("cannot copy or move a directory into itself")
+)
+
+// 항목별 처리 결과 알림 (dst가 없는 작업은 빈 문자열)
+type ItemFunc func(src, dst string, err error)
+
+// 파일 또는 디렉토리 이름 변경/이동
+func (sshCtx *SSHContext) Rename(oldPath, newPath string, overwrite bool) error {
+	oldPath, newPath = path.Clean(oldPath), path.Clean(newPath)
+	if oldPath == newPath {
+		return nil
+	}
+	if isSubPath(oldPath, newPath) {
+		return ErrIntoItself
+	}
+	if !overwrite {
+		if err := sshCtx.checkNotExists(newPath); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if overwrite {
+		err = sshCtx.posixRename(oldPath, newPath)
+	} else {
+		// SFTP v3 rename은 대상이 있으면 실패하므로 확인 이후에 생긴 파일도 덮어쓰지 않음
+		err = sshCtx.SFTPClient.Rename(oldPath, newPath)
+	}
+	if err == nil || !isGenericFailure(err) {
+		return err
+	}
+
+	// 파일 시스템이 다른 경우 mv로 처리 (-T: 대상 디렉토리 안으로 옮기지 않음)
+	flags := "-T -f"
+	if !overwrite {
+		if err := sshCtx.checkNotExists(newPath); err != nil {
+			return err
+		}
+		flags = "-T -n"
+	}
+	if _, err := sshCtx.ExecuteCommand("mv " + flags + " -- " + shellQuote(oldPath) + " " + shellQuote(newPath)); err != nil {
+		return errors.New("rename error: " + err.Error())
+	}
+	if !overwrite {
+		// mv -n은 대상이 있으면 아무것도 하지 않고 성공으로 끝날 수 있음
+		if _, err := sshCtx.SFTPClient.Lstat(oldPath); err == nil {
+			return ErrFileExists
+		}
+	}
+	return nil
+}
+
+// SFTP 서버가 원인을 구분하지 않은 실패인지 확인 (EXDEV 등은 SSH_FX_FAILURE로 전달됨)
+func isGenericFailure(err error) bool {
+	var statusErr *sftp.StatusError
+	return errors.As(err, &statusErr) && statusErr.FxCode() == sftp.ErrSSHFxFailure
+}
+
+// 대상이 있으면 교체하는 rename (posix-rename 확장을 지원하면 원자적으로 교체)
+//
+// 확장이 없으면 기존 대상을 임시 이름으로 옮겨 두고 교체하며, 실패하면 되돌린다.
+// 대상을 먼저 삭제하지 않으므로 교체에 실패해도 기존 파일은 남는다.
+func (sshCtx *SSHContext) posixRename(oldPath, newPath string) error {
+	if _, ok := sshCtx.SFTPClient.HasExtension("posix-rename@openssh.com"); ok {
+		return sshCtx.SFTPClient.PosixRename(oldPath, newPath)
+	}
+
+	// SFTP v3 rename은 대상이 없을 때만 성공
+	err := sshCtx.SFTPClient.Rename(oldPath, newPath)
+	if err == nil {
+		return nil
+	}
+	if _, statErr := sshCtx.SFTPClient.Lstat(newPath); statErr != nil {
+		return err
+	}
+
+	suffix, idErr := newUploadID()
+	if idErr != nil {
+		return idErr
+	}
+	backup := path.Join(path.Dir(newPath), "."+path.Base(newPath)+stagingInfix+suffix+".bak")
+	if err := sshCtx.SFTPClient.Rename(newPath, backup); err != nil {
+		return err
+	}
+	if err := sshCtx.SFTPClient.Rename(oldPath, newPath); err != nil {
+		if restoreErr := sshCtx.SFTPClient.Rename(backup, newPath); restoreErr != nil {
+			return errors.New(err.Error() + " (original file left at " + backup + ": " + restoreErr.Error() + ")")
+		}
+		return err
+	}
+	if err := sshCtx.RemoveAll(backup, func(string, string, error) {}); err != nil {
+		log.Println("failed to remove backup file:", err)
+	}
+	return nil
+}
+
+// 파일 또는 디렉토리 트리 복사
+func (sshCtx *SSHContext) Copy(src, dst string, overwrite bool, notify ItemFunc) error {
+	src, dst = path.Clean(src), path.Clean(dst)
+	if src == dst || isSubPath(src, dst) {
+		return ErrIntoItself
+	}
+	if !overwrite {
+		if err := sshCtx.checkNotExists(dst); err != nil {
+			return err
+		}
+	}
+	return sshCtx.copyTree(src, dst, notify)
+}
+
+func (sshCtx *SSHContext) copyTree(src, dst string, notify ItemFunc) error {
+	info, err := sshCtx.SFTPClient.Lstat(src)
+	if err != nil {
+		notify(src, dst, err)
+		return err
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		err = sshCtx.copySymlink(src, dst)
+	case info.IsDir():
+		if err = sshCtx.removeSymlink(dst); err == nil {
+			err = sshCtx.copyDir(src, dst, info.Mode().Perm(), notify)
+		} else {
+			notify(src, dst, err)
+		}
+	case info.Mode().IsRegular():
+		if err = sshCtx.removeSymlink(dst); err != nil {
+			break
+		}
+		if err = sshCtx.copyFile(src, dst); err == nil {
+			err = sshCtx.SFTPClient.Chmod(dst, info.Mode().Perm())
+		}
+	default:
+		err = errors.New("unsupported file type: " + info.Mode().Type().String())
+	}
+	if !info.IsDir() {
+		notify(src, dst, err)
+	}
+	return err
+}
+
+// 디렉토리 복사 (일부 하위 항목이 실패해도 나머지는 계속 진행)
+func (sshCtx *SSHContext) copyDir(src, dst string, perm os.FileMode, notify ItemFunc) error {
+	if err := sshCtx.SFTPClient.MkdirAll(dst); err != nil {
+		notify(src, dst, err)
+		return err
+	}
+	entries, err := sshCtx.SFTPClient.ReadDir(src)
+	if err != nil {
+		notify(src, dst, err)
+		return err
+	}
+
+	var firstErr error
+	for _, entry := range entries {
+		name := entry.Name()
+		if err := sshCtx.copyTree(path.Join(src, name), path.Join(dst, name), notify); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	// 읽기 전용 디렉토리도 복사할 수 있도록 권한은 마지막에 적용
+	if err := sshCtx.SFTPClient.Chmod(dst, perm); err != nil {
+		notify(src, dst, err)
+		return err
+	}
+	notify(src, dst, nil)
+	return firstErr
+}
+
+// 대상이 심볼릭 링크면 링크가 가리키는 파일에 쓰지 않도록 링크만 삭제
+func (sshCtx *SSHContext) removeSymlink(p string) error {
+	info, err := sshCtx.SFTPClient.Lstat(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return nil
+	}
+	return sshCtx.SFTPClient.Remove(p)
+}
+
+func (sshCtx *SSHContext) copySymlink(src, dst string) error {
+	target, err := sshCtx.SFTPClient.ReadLink(src)
+	if err != nil {
+		return err
+	}
+	if err := sshCtx.SFTPClient.Remove(dst); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return sshCtx.SFTPClient.Symlink(target, dst)
+}
+
+// 디렉토리 생성 (상위 디렉토리 포함)
+func (sshCtx *SSHContext) MakeDir(dir string) error {
+	return sshCtx.SFTPClient.MkdirAll(dir)
+}
+
+// 파일 또는 디렉토리를 하위 항목까지 삭제
+func (sshCtx *SSHContext) RemoveAll(target string, notify ItemFunc) error {
+	target = path.Clean(target)
+	if target == "/" {
+		return errors.New("refusing to remove /")
+	}
+
+	info, err := sshCtx.SFTPClient.Lstat(target)
+	if err != nil {
+		notify(target, "", err)
+		return err
+	}
+
+	if info.IsDir() {
+		entries, err := sshCtx.SFTPClient.ReadDir(target)
+		if err != nil {
+			notify(target, "", err)
+			return err
+		}
+		var firstErr error
+		for _, entry := range entries {
+			if err := sshCtx.RemoveAll(path.Join(target, entry.Name()), notify); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		if firstErr != nil {
+			// 하위 항목이 남아 있으면 디렉토리도 삭제할 수 없음
+			return firstErr
+		}
+		err = sshCtx.SFTPClient.RemoveDirectory(target)
+	} else {
+		err = sshCtx.SFTPClient.Remove(target)
+	}
+	notify(target, "", err)
+	return err
+}
+
+func (sshCtx *SSHContext) checkNotExists(p string) error {
+	_, err := sshCtx.SFTPClient.Lstat(p)
+	if err == nil {
+		return ErrFileExists
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// child가 parent 디렉토리 아래 경로인지 확인
+func isSubPath(parent, child string) bool {
+	return strings.HasPrefix(child, strings.TrimSuffix(parent, "/")+"/")
+}
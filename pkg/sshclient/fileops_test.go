@@ -0,0 +1,200 @@
+package sshclient
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// 테스트 임시 디렉토리와 다른 파일 시스템의 디렉토리 (mv 대체 경로 확인용)
+func otherDeviceDir(t *testing.T, than string) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("/dev/shm", "sshbck-test-")
+	if err != nil {
+		t.Skip("no second file system available:", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	var a, b syscall.Stat_t
+	if syscall.Stat(dir, &a) != nil || syscall.Stat(than, &b) != nil || a.Dev == b.Dev {
+		t.Skip("/dev/shm is on the same file system")
+	}
+	return dir
+}
+
+func writeFile(t *testing.T, p, content string) {
+	t.Helper()
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, p string) string {
+	t.Helper()
+	data, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRenameHostileNames(t *testing.T) {
+	work, src, dst := t.TempDir(), t.TempDir(), t.TempDir()
+	sshCtx := newTestContext(t, work)
+
+	for _, name := range hostileNames {
+		writeFile(t, filepath.Join(src, name), name)
+		if err := sshCtx.Rename(filepath.Join(src, name), filepath.Join(dst, name), false); err != nil {
+			t.Fatalf("Rename(%q): %v", name, err)
+		}
+		if got := readFile(t, filepath.Join(dst, name)); got != name {
+			t.Errorf("Rename(%q) content = %q", name, got)
+		}
+	}
+
+	if left := listTree(t, src); len(left) != 0 {
+		t.Errorf("entries left in source: %v", left)
+	}
+	if moved := listTree(t, dst); len(moved) != len(hostileNames) {
+		t.Errorf("moved %d entries, want %d: %v", len(moved), len(hostileNames), moved)
+	}
+	assertNotPwned(t, work, src, dst)
+}
+
+func TestRenameNoOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	sshCtx := newTestContext(t, t.TempDir())
+
+	writeFile(t, filepath.Join(dir, "a"), "a")
+	writeFile(t, filepath.Join(dir, "b"), "b")
+	if err := sshCtx.Rename(filepath.Join(dir, "a"), filepath.Join(dir, "b"), false); !errors.Is(err, ErrFileExists) {
+		t.Fatalf("Rename onto existing file = %v, want ErrFileExists", err)
+	}
+	if readFile(t, filepath.Join(dir, "a")) != "a" || readFile(t, filepath.Join(dir, "b")) != "b" {
+		t.Error("files changed after refused rename")
+	}
+}
+
+// 파일 시스템이 다른 경우 SFTP rename이 실패하고 mv로 처리되는지 확인
+func TestRenameCrossDeviceFallback(t *testing.T) {
+	work, src := t.TempDir(), t.TempDir()
+	dst := otherDeviceDir(t, src)
+	sshCtx := newTestContext(t, work)
+
+	for _, name := range hostileNames {
+		writeFile(t, filepath.Join(src, name), name)
+		if err := sshCtx.Rename(filepath.Join(src, name), filepath.Join(dst, name), false); err != nil {
+			t.Fatalf("Rename(%q) across devices: %v", name, err)
+		}
+		if got := readFile(t, filepath.Join(dst, name)); got != name {
+			t.Errorf("Rename(%q) content = %q", name, got)
+		}
+	}
+
+	if left := listTree(t, src); len(left) != 0 {
+		t.Errorf("entries left in source: %v", left)
+	}
+	if moved := listTree(t, dst); len(moved) != len(hostileNames) {
+		t.Errorf("moved %d entries, want %d: %v", len(moved), len(hostileNames), moved)
+	}
+	assertNotPwned(t, work, src, dst)
+}
+
+func TestRenameCrossDeviceOverwrite(t *testing.T) {
+	src := t.TempDir()
+	dst := otherDeviceDir(t, src)
+	sshCtx := newTestContext(t, t.TempDir())
+
+	// 덮어쓰지 않는 경우 기존 파일 유지
+	writeFile(t, filepath.Join(src, "file"), "new")
+	writeFile(t, filepath.Join(dst, "file"), "old")
+	if err := sshCtx.Rename(filepath.Join(src, "file"), filepath.Join(dst, "file"), false); !errors.Is(err, ErrFileExists) {
+		t.Fatalf("Rename onto existing file = %v, want ErrFileExists", err)
+	}
+	if readFile(t, filepath.Join(dst, "file")) != "old" {
+		t.Error("existing file overwritten without overwrite")
+	}
+
+	// 덮어쓰는 경우 교체
+	if err := sshCtx.Rename(filepath.Join(src, "file"), filepath.Join(dst, "file"), true); err != nil {
+		t.Fatalf("Rename with overwrite: %v", err)
+	}
+	if readFile(t, filepath.Join(dst, "file")) != "new" {
+		t.Error("existing file not replaced")
+	}
+
+	// 대상이 디렉토리면 그 안으로 옮기지 않음
+	if err := os.Mkdir(filepath.Join(src, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(src, "dir", "inner"), "inner")
+	if err := os.Mkdir(filepath.Join(dst, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dst, "dir", "keep"), "keep")
+	if err := sshCtx.Rename(filepath.Join(src, "dir"), filepath.Join(dst, "dir"), true); err == nil {
+		t.Error("Rename onto non-empty directory succeeded")
+	}
+	if _, err := os.Lstat(filepath.Join(dst, "dir", "dir")); err == nil {
+		t.Error("directory moved into existing directory")
+	}
+	if readFile(t, filepath.Join(dst, "dir", "keep")) != "keep" {
+		t.Error("existing directory content changed")
+	}
+}
+
+// SFTP 서버가 원인을 알려주는 오류는 mv로 다시 시도하지 않음
+func TestRenameMissingSource(t *testing.T) {
+	dir := t.TempDir()
+	sshCtx := newTestContext(t, t.TempDir())
+
+	err := sshCtx.Rename(filepath.Join(dir, "missing"), filepath.Join(dir, "target"), false)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Rename of missing source = %v, want ErrNotExist", err)
+	}
+}
+
+// 덮어쓰는 대상이 심볼릭 링크면 링크가 가리키는 파일이 아니라 링크를 교체
+func TestCopyOverwriteSymlink(t *testing.T) {
+	src, dst, outside := t.TempDir(), t.TempDir(), t.TempDir()
+	sshCtx := newTestContext(t, t.TempDir())
+	notify := func(string, string, error) {}
+
+	writeFile(t, filepath.Join(src, "file"), "new")
+	writeFile(t, filepath.Join(outside, "secret"), "secret")
+	if err := os.Symlink(filepath.Join(outside, "secret"), filepath.Join(dst, "file")); err != nil {
+		t.Fatal(err)
+	}
+	if err := sshCtx.Copy(filepath.Join(src, "file"), filepath.Join(dst, "file"), true, notify); err != nil {
+		t.Fatalf("Copy onto symlink: %v", err)
+	}
+	if readFile(t, filepath.Join(outside, "secret")) != "secret" {
+		t.Error("symlink target overwritten")
+	}
+	if info, err := os.Lstat(filepath.Join(dst, "file")); err != nil || !info.Mode().IsRegular() {
+		t.Errorf("destination is not a regular file: %v", err)
+	}
+	if readFile(t, filepath.Join(dst, "file")) != "new" {
+		t.Error("destination not replaced")
+	}
+
+	// 디렉토리 복사도 링크된 디렉토리 안에 쓰지 않음
+	if err := os.Mkdir(filepath.Join(src, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(src, "dir", "inner"), "inner")
+	if err := os.Symlink(outside, filepath.Join(dst, "dir")); err != nil {
+		t.Fatal(err)
+	}
+	if err := sshCtx.Copy(filepath.Join(src, "dir"), filepath.Join(dst, "dir"), true, notify); err != nil {
+		t.Fatalf("Copy directory onto symlink: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(outside, "inner")); err == nil {
+		t.Error("directory copied through symlink")
+	}
+	if readFile(t, filepath.Join(dst, "dir", "inner")) != "inner" {
+		t.Error("directory not copied")
+	}
+}
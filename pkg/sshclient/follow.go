@@ -0,0 +1,129 @@
+package sshclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// 추가된 내용을 한 번에 읽는 최대 크기
+const followReadSize = 32 * 1024
+
+// 따라 읽는 중 발생한 파일 변경
+type FollowEvent string
+
+const (
+	FollowTruncated FollowEvent = "truncated" // 파일 크기가 줄어 처음부터 다시 읽음
+	FollowRotated   FollowEvent = "rotated"   // 경로가 새 파일로 바뀌어 새 파일을 처음부터 읽음
+)
+
+// 파일에 추가되는 내용을 interval 간격으로 확인하며 emit으로 전달 (ctx가 끝날 때까지)
+//
+// offset이 음수면 현재 파일 끝부터 읽는다. 이벤트 알림은 data 없이 event만 전달된다.
+// SFTP는 inode를 알려주지 않으므로 경로의 크기/수정 시각이 그 전후에 확인한
+// 열린 핸들의 값 사이에 있지 않은 상태가 연속 두 번 나오면 로테이션된 것으로 판단한다.
+func (sshCtx *SSHContext) Follow(ctx context.Context, p string, offset int64, interval time.Duration, emit func(data []byte, event FollowEvent) error) error {
+	file, err := sshCtx.SFTPClient.Open(p)
+	if err != nil {
+		return err
+	}
+	defer func() { file.Close() }()
+
+	if offset < 0 {
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		offset = info.Size()
+	}
+
+	buf := make([]byte, followReadSize)
+	drain := func() error {
+		for {
+			n, err := file.ReadAt(buf, offset)
+			if n > 0 {
+				offset += int64(n)
+				if emitErr := emit(buf[:n], ""); emitErr != nil {
+					return emitErr
+				}
+			}
+			if err == io.EOF || n == 0 {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	suspect := false
+	for {
+		if err := drain(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		if current.Size() < offset {
+			offset = 0
+			if err := emit(nil, FollowTruncated); err != nil {
+				return err
+			}
+			continue
+		}
+
+		latest, err := sshCtx.SFTPClient.Stat(p)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		after, statErr := file.Stat()
+		if statErr != nil {
+			return statErr
+		}
+		if err == nil && sameFile(current, latest, after) {
+			suspect = false
+			continue
+		}
+		if !suspect {
+			// 쓰는 도중에 확인했을 수 있으므로 다음 확인까지 기다림
+			suspect = true
+			continue
+		}
+		if err != nil {
+			// 새 파일이 아직 생성되지 않음
+			continue
+		}
+
+		// 이전 파일에 남은 내용을 마저 읽고 새 파일로 전환
+		if err := drain(); err != nil {
+			return err
+		}
+		next, err := sshCtx.SFTPClient.Open(p)
+		if err != nil {
+			return err
+		}
+		file.Close()
+		file, offset, suspect = next, 0, false
+		if err := emit(nil, FollowRotated); err != nil {
+			return err
+		}
+	}
+}
+
+// 경로의 상태가 전후로 확인한 열린 파일의 상태 사이에 있으면 같은 파일로 판단
+func sameFile(before, latest, after os.FileInfo) bool {
+	return before.Size() <= latest.Size() && latest.Size() <= after.Size() &&
+		!latest.ModTime().Before(before.ModTime()) && !latest.ModTime().After(after.ModTime())
+}
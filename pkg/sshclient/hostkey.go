@@ -0,0 +1,133 @@
+package sshclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// 호스트 키 검증 정책
+type HostKeyPolicy string
+
+const (
+	HostKeyStrict HostKeyPolicy = "strict" // known_hosts에 등록된 키만 허용
+	HostKeyTOFU   HostKeyPolicy = "tofu"   // 처음 보는 호스트는 확인 후 등록
+)
+
+var (
+	ErrHostKeyMismatch = errors.New("host key mismatch")
+	ErrHostKeyUnknown  = errors.New("unknown host key")
+	ErrHostKeyRejected = errors.New("host key rejected")
+)
+
+// 호스트 키 검증 실패
+type HostKeyError struct {
+	Hostname    string
+	KeyType     string
+	Fingerprint string
+	Err         error
+}
+
+func (e *HostKeyError) Error() string {
+	return fmt.Sprintf("%v for %s (%s %s)", e.Err, e.Hostname, e.KeyType, e.Fingerprint)
+}
+
+func (e *HostKeyError) Unwrap() error {
+	return e.Err
+}
+
+// 처음 보는 호스트 키를 신뢰할지 확인하는 함수
+type HostKeyConfirm func(hostname string, key ssh.PublicKey) (bool, error)
+
+// 브릿지가 관리하는 known_hosts 파일
+type KnownHosts struct {
+	path string
+	mu   sync.Mutex
+}
+
+// known_hosts 파일 열기 (없으면 생성)
+func NewKnownHosts(path string) (*KnownHosts, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+
+	return &KnownHosts{path: path}, nil
+}
+
+// 정책에 따라 호스트 키를 검증하는 콜백 생성
+func (k *KnownHosts) Callback(policy HostKeyPolicy, confirm HostKeyConfirm) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := k.check(hostname, remote, key)
+
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+
+		hostKeyErr := &HostKeyError{
+			Hostname:    hostname,
+			KeyType:     key.Type(),
+			Fingerprint: ssh.FingerprintSHA256(key),
+		}
+		if len(keyErr.Want) > 0 {
+			hostKeyErr.Err = ErrHostKeyMismatch
+			return hostKeyErr
+		}
+
+		if policy != HostKeyTOFU || confirm == nil {
+			hostKeyErr.Err = ErrHostKeyUnknown
+			return hostKeyErr
+		}
+
+		accepted, err := confirm(hostname, key)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			hostKeyErr.Err = ErrHostKeyRejected
+			return hostKeyErr
+		}
+		return k.Add(hostname, key)
+	}
+}
+
+// known_hosts에 호스트 키 추가
+func (k *KnownHosts) Add(hostname string, key ssh.PublicKey) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	file, err := os.OpenFile(k.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	line := knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)
+	if _, err := file.WriteString(line + "\n"); err != nil {
+		return fmt.Errorf("failed to write known_hosts: %v", err)
+	}
+	return nil
+}
+
+// known_hosts 파일과 대조
+func (k *KnownHosts) check(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	callback, err := knownhosts.New(k.path)
+	if err != nil {
+		return err
+	}
+	return callback(hostname, remote, key)
+}
@@ -0,0 +1,101 @@
+package sshclient
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/sftp"
+)
+
+// 권한 변경 (recursive면 하위 항목까지, 심볼릭 링크는 건너뜀)
+func (sshCtx *SSHContext) Chmod(target string, mode os.FileMode, recursive bool, notify ItemFunc) error {
+	return sshCtx.walk(target, recursive, notify, func(p string, info os.FileInfo) error {
+		return sshCtx.SFTPClient.Chmod(p, mode)
+	})
+}
+
+// 소유자 및 그룹 변경 (빈 값은 변경하지 않음, recursive면 하위 항목까지)
+func (sshCtx *SSHContext) Chown(target, owner, group string, recursive bool, notify ItemFunc) error {
+	uid, gid := -1, -1
+	var err error
+	if owner != "" {
+		if uid, err = sshCtx.lookupID("passwd", owner); err != nil {
+			return err
+		}
+	}
+	if group != "" {
+		if gid, err = sshCtx.lookupID("group", group); err != nil {
+			return err
+		}
+	}
+
+	return sshCtx.walk(target, recursive, notify, func(p string, info os.FileInfo) error {
+		stat, ok := info.Sys().(*sftp.FileStat)
+		if !ok {
+			return errors.New("ownership is not available: " + p)
+		}
+		newUID, newGID := int(stat.UID), int(stat.GID)
+		if uid >= 0 {
+			newUID = uid
+		}
+		if gid >= 0 {
+			newGID = gid
+		}
+		return sshCtx.SFTPClient.Chown(p, newUID, newGID)
+	})
+}
+
+// 대상과 (recursive면) 하위 항목에 fn 적용
+func (sshCtx *SSHContext) walk(target string, recursive bool, notify ItemFunc, fn func(p string, info os.FileInfo) error) error {
+	info, err := sshCtx.SFTPClient.Stat(target)
+	if err != nil {
+		notify(target, "", err)
+		return err
+	}
+
+	err = fn(target, info)
+	notify(target, "", err)
+	if err != nil || !recursive || !info.IsDir() {
+		return err
+	}
+
+	entries, err := sshCtx.SFTPClient.ReadDir(target)
+	if err != nil {
+		notify(target, "", err)
+		return err
+	}
+	var firstErr error
+	for _, entry := range entries {
+		if entry.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+		if err := sshCtx.walk(path.Join(target, entry.Name()), true, notify, fn); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// 사용자 또는 그룹 이름을 ID로 변환 (db는 passwd 또는 group)
+func (sshCtx *SSHContext) lookupID(db, name string) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil && id >= 0 {
+		return id, nil
+	}
+
+	out, err := sshCtx.ExecuteCommand("getent " + db + " -- " + shellQuote(name))
+	if err != nil {
+		return -1, errors.New("unknown " + db + " entry: " + name)
+	}
+	fields := strings.Split(strings.TrimSpace(out), ":")
+	if len(fields) < 3 {
+		return -1, errors.New("unknown " + db + " entry: " + name)
+	}
+	id, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return -1, errors.New("invalid " + db + " entry: " + name)
+	}
+	return id, nil
+}
@@ -0,0 +1,118 @@
+package sshclient
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// 마지막 N줄을 찾을 때 한 번에 거꾸로 읽는 크기
+const tailReadSize = 16 * 1024
+
+// 파일의 일부 구간
+type FileRange struct {
+	Offset  int64 // 읽은 구간의 시작 위치
+	Size    int64 // 전체 파일 크기
+	Content []byte
+}
+
+// offset부터 length 바이트 읽기 (파일 끝을 넘으면 끝까지)
+func (sshCtx *SSHContext) ReadRange(p string, offset, length int64) (FileRange, error) {
+	file, err := sshCtx.SFTPClient.Open(p)
+	if err != nil {
+		return FileRange{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return FileRange{}, err
+	}
+	result := FileRange{Offset: offset, Size: info.Size()}
+	if offset < 0 || length < 0 {
+		return result, errors.New("invalid range")
+	}
+	if offset >= result.Size {
+		return result, nil
+	}
+	if remain := result.Size - offset; length > remain {
+		length = remain
+	}
+
+	result.Content = make([]byte, length)
+	n, err := file.ReadAt(result.Content, offset)
+	result.Content = result.Content[:n]
+	if err != nil && err != io.EOF {
+		return result, err
+	}
+	return result, nil
+}
+
+// 마지막 length 바이트 읽기
+func (sshCtx *SSHContext) ReadLastBytes(p string, length int64) (FileRange, error) {
+	info, err := sshCtx.SFTPClient.Stat(p)
+	if err != nil {
+		return FileRange{}, err
+	}
+	offset := info.Size() - length
+	if offset < 0 {
+		offset = 0
+	}
+	return sshCtx.ReadRange(p, offset, length)
+}
+
+// 마지막 lines 줄 읽기 (최대 maxBytes 까지만 거꾸로 탐색)
+func (sshCtx *SSHContext) ReadLastLines(p string, lines int, maxBytes int64) (FileRange, error) {
+	file, err := sshCtx.SFTPClient.Open(p)
+	if err != nil {
+		return FileRange{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return FileRange{}, err
+	}
+	size := info.Size()
+
+	// 끝에서부터 블록 단위로 읽으며 줄바꿈 개수 확인 (마지막 줄바꿈은 줄 끝으로 취급)
+	var buf []byte
+	offset := size
+	for offset > 0 && int64(len(buf)) < maxBytes {
+		n := int64(tailReadSize)
+		if n > offset {
+			n = offset
+		}
+		if limit := maxBytes - int64(len(buf)); n > limit {
+			n = limit
+		}
+		offset -= n
+
+		block := make([]byte, n)
+		if _, err := file.ReadAt(block, offset); err != nil && err != io.EOF {
+			return FileRange{}, err
+		}
+		buf = append(block, buf...)
+
+		if bytes.Count(bytes.TrimSuffix(buf, []byte("\n")), []byte("\n")) >= lines {
+			break
+		}
+	}
+
+	// 필요한 줄 수를 넘는 앞부분은 잘라냄
+	body := bytes.TrimSuffix(buf, []byte("\n"))
+	for count := 0; ; {
+		idx := bytes.LastIndexByte(body, '\n')
+		if idx < 0 {
+			break
+		}
+		if count++; count == lines {
+			offset += int64(idx + 1)
+			buf = buf[idx+1:]
+			break
+		}
+		body = body[:idx]
+	}
+
+	return FileRange{Offset: offset, Size: size, Content: buf}, nil
+}
@@ -0,0 +1,180 @@
+package sshclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 내용 검색 결과 제한
+const (
+	maxLineMatches = 100      // 파일 하나에서 보고하는 최대 줄 수
+	maxMatchText   = 512      // 보고하는 줄의 최대 길이
+	maxScanLine    = 1 << 20  // 이보다 긴 줄이 있으면 나머지 내용은 검색하지 않음
+	binaryCheckLen = 8 * 1024 // 앞부분에 NUL이 있으면 바이너리 파일로 보고 건너뜀
+)
+
+// 파일 검색 조건 (디렉토리는 결과에 포함하지 않음)
+type SearchOptions struct {
+	Name    []string       // 파일 이름 또는 상대 경로 glob (비어 있으면 모든 파일)
+	Content *regexp.Regexp // 지정하면 일치하는 줄이 있는 일반 파일만 포함
+
+	MinSize        int64
+	MaxSize        int64 // 0이면 제한 없음
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+
+	MaxDepth    int   // root 아래 탐색 깊이 (0이면 제한 없음)
+	MaxResults  int   // 0이면 제한 없음
+	MaxFileSize int64 // 내용을 검색할 파일 크기 제한 (0이면 제한 없음)
+}
+
+// 검색된 파일
+type SearchMatch struct {
+	Path    string      `json:"path"`
+	Size    int64       `json:"size"`
+	ModTime time.Time   `json:"modTime"`
+	Lines   []LineMatch `json:"lines,omitempty"` // 내용 검색 시 일치한 줄
+}
+
+type LineMatch struct {
+	Line int    `json:"line"` // 1부터 시작
+	Text string `json:"text"`
+}
+
+// 검색 통계
+type SearchStats struct {
+	Scanned   int  `json:"scanned"` // 조건을 확인한 파일 수
+	Matches   int  `json:"matches"`
+	Truncated bool `json:"truncated"` // 결과 수 제한으로 중단됨
+}
+
+// root 아래를 SFTP로 탐색하며 조건에 맞는 파일을 찾을 때마다 emit 호출
+//
+// 심볼릭 링크는 따라가지 않으며 읽을 수 없는 디렉토리와 파일은 건너뛴다.
+func (sshCtx *SSHContext) Search(ctx context.Context, root string, opts SearchOptions, emit func(SearchMatch) error) (SearchStats, error) {
+	var stats SearchStats
+	root = path.Clean(root)
+	info, err := sshCtx.SFTPClient.Stat(root)
+	if err != nil {
+		return stats, err
+	}
+	if !info.IsDir() {
+		return stats, errors.New("not a directory: " + root)
+	}
+
+	walker := sshCtx.SFTPClient.Walk(root)
+	for walker.Step() {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		if walker.Err() != nil {
+			// 권한이 없는 디렉토리 등
+			continue
+		}
+
+		p, info := walker.Path(), walker.Stat()
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+		if info.IsDir() {
+			if opts.MaxDepth > 0 && rel != "" && strings.Count(rel, "/")+1 >= opts.MaxDepth {
+				walker.SkipDir()
+			}
+			continue
+		}
+
+		stats.Scanned++
+		if !opts.matchInfo(rel, info) {
+			continue
+		}
+		match := SearchMatch{Path: p, Size: info.Size(), ModTime: info.ModTime()}
+		if opts.Content != nil {
+			if !info.Mode().IsRegular() || (opts.MaxFileSize > 0 && info.Size() > opts.MaxFileSize) {
+				continue
+			}
+			lines, err := sshCtx.grepFile(ctx, p, opts.Content)
+			if err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return stats, ctxErr
+				}
+				continue
+			}
+			if len(lines) == 0 {
+				continue
+			}
+			match.Lines = lines
+		}
+
+		if err := emit(match); err != nil {
+			return stats, err
+		}
+		if stats.Matches++; opts.MaxResults > 0 && stats.Matches >= opts.MaxResults {
+			stats.Truncated = true
+			break
+		}
+	}
+	return stats, nil
+}
+
+// 이름 (상대 경로 또는 파일 이름), 크기, 수정 시각 조건 확인
+func (opts *SearchOptions) matchInfo(rel string, info os.FileInfo) bool {
+	if len(opts.Name) > 0 && !matchAny(opts.Name, rel) {
+		return false
+	}
+	if info.Size() < opts.MinSize || (opts.MaxSize > 0 && info.Size() > opts.MaxSize) {
+		return false
+	}
+	if !opts.ModifiedAfter.IsZero() && info.ModTime().Before(opts.ModifiedAfter) {
+		return false
+	}
+	if !opts.ModifiedBefore.IsZero() && info.ModTime().After(opts.ModifiedBefore) {
+		return false
+	}
+	return true
+}
+
+// 파일에서 정규식과 일치하는 줄 찾기 (바이너리 파일은 빈 결과)
+func (sshCtx *SSHContext) grepFile(ctx context.Context, p string, re *regexp.Regexp) ([]LineMatch, error) {
+	file, err := sshCtx.SFTPClient.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, binaryCheckLen)
+	if head, _ := reader.Peek(binaryCheckLen); bytes.IndexByte(head, 0) >= 0 {
+		return nil, nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxScanLine)
+
+	var lines []LineMatch
+	for n := 1; scanner.Scan(); n++ {
+		if n%1000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		line := scanner.Bytes()
+		if !re.Match(line) {
+			continue
+		}
+		if len(line) > maxMatchText {
+			line = line[:maxMatchText]
+		}
+		lines = append(lines, LineMatch{Line: n, Text: strings.ToValidUTF8(string(line), "�")})
+		if len(lines) >= maxLineMatches {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, bufio.ErrTooLong) {
+		return lines, err
+	}
+	return lines, nil
+}
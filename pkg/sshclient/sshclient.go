@@ -2,28 +2,31 @@ package sshclient
 
 import (
 	"bytes"
-	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"os"
+	"path"
 	"strings"
 	"sync"
-
-	"sshbck/pkg/queue"
+	"time"
 
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 )
 
 type SSHContext struct {
-	Stdin  io.WriteCloser
-	Stdout io.Reader
-	Queue  *queue.Queue
-
 	Client     *ssh.Client
-	Session    *ssh.Session
 	SFTPClient *sftp.Client
+	User       string // 접속한 사용자
+	Address    string // 접속한 대상 호스트 주소
+
+	terminals map[string]*Terminal // 채널 ID -> PTY 채널
+	termMutex sync.Mutex
+
+	uploads uploadRegistry
 
 	userCache  map[uint32]string // UID -> Username cache
 	groupCache map[uint32]string // GID -> Groupname cache
@@ -32,8 +35,29 @@ type SSHContext struct {
 
 type Config struct {
 	ServerConfig *ssh.ClientConfig
+	Auth         *AuthConfig // 설정 시 ServerConfig의 User/Auth를 대체
 	Protocol     string
 	Address      string
+	JumpHosts    []Config // 순서대로 경유할 점프 호스트 (ProxyJump)
+
+	// 연결 단계 알림 (선택)
+	Notify func(stage Stage, hop int, address string)
+}
+
+// 연결 단계
+type Stage string
+
+const (
+	StageDialing        Stage = "dialing"
+	StageAuthenticating Stage = "authenticating"
+)
+
+// 특정 홉(점프 호스트 또는 대상 호스트) 연결 실패
+type HopError struct {
+	Hop     int // 0부터 시작, len(JumpHosts)이면 대상 호스트
+	Address string
+	Target  bool
+	Err     error
 }
 
 type FileInfo struct {
@@ -43,24 +67,124 @@ type FileInfo struct {
 	Group string `json:"group"`
 	Perm  string `json:"perm"`
 	Size  int64  `json:"size"`
+
+	Type       FileType  `json:"type"`
+	Mode       string    `json:"mode"` // 특수 권한을 포함한 8진수 (예: "4755")
+	Setuid     bool      `json:"setuid,omitempty"`
+	Setgid     bool      `json:"setgid,omitempty"`
+	Sticky     bool      `json:"sticky,omitempty"`
+	Hidden     bool      `json:"hidden,omitempty"` // 이름이 .으로 시작
+	ModTime    time.Time `json:"modTime"`
+	AccessTime time.Time `json:"accessTime"`
+	MIME       string    `json:"mime,omitempty"` // 확장자로 추측한 MIME 타입 (일반 파일과 그 링크)
+
+	// 심볼릭 링크 정보 (IsDir은 링크 자체 기준이므로 디렉토리 링크는 TargetType으로 판단)
+	LinkTarget string   `json:"linkTarget,omitempty"`
+	TargetType FileType `json:"targetType,omitempty"`
+	Broken     bool     `json:"broken,omitempty"` // 링크 대상이 없음
 }
 
 // SSH context 생성
 func NewSSHContext() *SSHContext {
 	return &SSHContext{
-		Queue:      queue.NewQueue(),
+		terminals:  make(map[string]*Terminal),
+		uploads:    uploadRegistry{uploads: make(map[string]*Upload)},
 		userCache:  make(map[uint32]string),
 		groupCache: make(map[uint32]string),
 	}
 }
 
-// SSH 연결 생성 함수
+func (e *HopError) Error() string {
+	if e.Target {
+		return fmt.Sprintf("target %s: %v", e.Address, e.Err)
+	}
+	return fmt.Sprintf("jump host %d (%s): %v", e.Hop+1, e.Address, e.Err)
+}
+
+func (e *HopError) Unwrap() error {
+	return e.Err
+}
+
+// SSH 연결 생성 함수 (점프 호스트가 있으면 direct-tcpip 채널로 경유)
 func (cfg Config) NewConn() (*ssh.Client, error) {
-	conn, err := ssh.Dial(cfg.Protocol, cfg.Address, cfg.ServerConfig)
+	hops := append(append([]Config{}, cfg.JumpHosts...), cfg)
+	clients := make([]*ssh.Client, 0, len(hops))
+
+	for i, hop := range hops {
+		client, err := hop.dial(clients, func(stage Stage) {
+			if cfg.Notify != nil {
+				cfg.Notify(stage, i, hop.Address)
+			}
+		})
+		if err != nil {
+			closeClients(clients)
+			return nil, &HopError{Hop: i, Address: hop.Address, Target: i == len(hops)-1, Err: err}
+		}
+		clients = append(clients, client)
+	}
+
+	conn := clients[len(clients)-1]
+	if jumps := clients[:len(clients)-1]; len(jumps) > 0 {
+		// 대상 연결이 끊어지면 경유한 점프 호스트 연결도 정리
+		go func() {
+			conn.Wait()
+			closeClients(jumps)
+		}()
+	}
+	return conn, nil
+}
+
+// 이전 홉을 통해 (없으면 직접) 연결
+func (cfg Config) dial(prev []*ssh.Client, notify func(stage Stage)) (*ssh.Client, error) {
+	serverConfig := &ssh.ClientConfig{}
+	if cfg.ServerConfig != nil {
+		*serverConfig = *cfg.ServerConfig
+	}
+
+	if cfg.Auth != nil {
+		methods, closer, err := cfg.Auth.Methods()
+		if err != nil {
+			return nil, err
+		}
+		defer closer.Close()
+
+		if cfg.Auth.User != "" {
+			serverConfig.User = cfg.Auth.User
+		}
+		serverConfig.Auth = methods
+	}
+
+	notify(StageDialing)
+
+	var netConn net.Conn
+	var err error
+	if len(prev) == 0 {
+		protocol := cfg.Protocol
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		netConn, err = net.DialTimeout(protocol, cfg.Address, serverConfig.Timeout)
+	} else {
+		netConn, err = prev[len(prev)-1].Dial("tcp", cfg.Address)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return conn, nil
+
+	notify(StageAuthenticating)
+	conn, chans, reqs, err := ssh.NewClientConn(netConn, cfg.Address, serverConfig)
+	if err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(conn, chans, reqs), nil
+}
+
+// 연결을 역순으로 종료
+func closeClients(clients []*ssh.Client) {
+	for i := len(clients) - 1; i >= 0; i-- {
+		clients[i].Close()
+	}
 }
 
 // SSH 세션 생성 함수
@@ -72,28 +196,6 @@ func (cfg Config) NewSession(conn *ssh.Client) (*ssh.Session, error) {
 	return session, nil
 }
 
-// Stdout를 지속적으로 읽고 출력 내용을 Queue에 추가
-func (sshCtx *SSHContext) Read(ctx context.Context) {
-	buf := make([]byte, 1024)
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Read context done")
-			return
-		default:
-			n, err := sshCtx.Stdout.Read(buf)
-			if err != nil && err != io.EOF {
-				log.Println("I/O read error:", err)
-				return
-			}
-			if n > 0 {
-				sshCtx.Queue.Push(buf[:n])
-				buf = make([]byte, 1024) // Clear buffer
-			}
-		}
-	}
-}
-
 // SSH 명령 실행
 func (sshCtx *SSHContext) ExecuteCommand(cmd string) (string, error) {
 	var stdoutBuf bytes.Buffer
@@ -120,7 +222,8 @@ func (sshCtx *SSHContext) getOwnerGroupName(uid, gid uint32) (string, string) {
 	ownerName, found := sshCtx.userCache[uid]
 	if !found {
 		ownerCmd := fmt.Sprintf("getent passwd %d | cut -d: -f1", uid)
-		ownerName, err := sshCtx.ExecuteCommand(ownerCmd)
+		var err error
+		ownerName, err = sshCtx.ExecuteCommand(ownerCmd)
 		if err != nil {
 			log.Printf("Failed to get owner name for UID %d: %v", uid, err)
 			ownerName = fmt.Sprintf("%d", uid)
@@ -133,7 +236,8 @@ func (sshCtx *SSHContext) getOwnerGroupName(uid, gid uint32) (string, string) {
 	groupName, found := sshCtx.groupCache[gid]
 	if !found {
 		groupCmd := fmt.Sprintf("getent group %d | cut -d: -f1", gid)
-		groupName, err := sshCtx.ExecuteCommand(groupCmd)
+		var err error
+		groupName, err = sshCtx.ExecuteCommand(groupCmd)
 		if err != nil {
 			log.Printf("Failed to get group name for GID %d: %v", gid, err)
 			groupName = fmt.Sprintf("%d", gid)
@@ -147,6 +251,12 @@ func (sshCtx *SSHContext) getOwnerGroupName(uid, gid uint32) (string, string) {
 
 // 홈 디렉토리 반환
 func (sshCtx *SSHContext) HomeDir() (string, error) {
+	// SFTP 세션의 작업 디렉토리는 로그인 사용자의 홈 디렉토리
+	if sshCtx.SFTPClient != nil {
+		if homeDir, err := sshCtx.SFTPClient.Getwd(); err == nil {
+			return homeDir, nil
+		}
+	}
 	homeDir, err := sshCtx.ExecuteCommand("pwd")
 	if err != nil {
 		return "", err
@@ -163,54 +273,55 @@ func (sshCtx *SSHContext) GetFile(path string) (*sftp.File, error) {
 	return file, nil
 }
 
-// 원격 SSH Server에 파일 쓰기
-func (sshCtx *SSHContext) SaveFileChunkWithChecksum(path string, content []byte, isFirstChunk bool, isLastChunk bool, checksum string) error {
-	tmpPath := "/tmp/" + strings.ReplaceAll(path, "/", "_") + ".tmp"
-	var file *sftp.File
-	var err error
+// 원격 SSH Server에 파일 쓰기 (청크를 순서대로 이어 붙이고 마지막 청크에서 체크섬 검증 후 저장)
+// 마지막 청크를 저장하면 저장된 파일의 버전을 반환
+func (sshCtx *SSHContext) SaveFileChunkWithChecksum(path string, content []byte, isFirstChunk bool, isLastChunk bool, checksum string, baseVersion string) (string, error) {
+	id := "chunked:" + path
 
+	var upload *Upload
+	var err error
 	if isFirstChunk {
-		file, err = sshCtx.SFTPClient.Create(tmpPath)
+		upload, err = sshCtx.startUpload(id, path, -1, ChecksumSHA256)
 	} else {
-		file, err = sshCtx.SFTPClient.OpenFile(tmpPath, os.O_WRONLY|os.O_APPEND)
+		upload, err = sshCtx.Upload(id)
 	}
-
 	if err != nil {
-		return fmt.Errorf("failed to open file: %v", err)
+		return "", fmt.Errorf("failed to open file: %v", err)
 	}
-	defer file.Close()
 
-	_, err = file.Write(content)
-	if err != nil {
-		return fmt.Errorf("faild to write content: %v", err)
+	if err := upload.WriteAt(upload.Received(), content); err != nil {
+		sshCtx.AbortUpload(id)
+		return "", err
 	}
 
-	if isLastChunk {
-		cmd := fmt.Sprintf("sha256sum %s | awk '{print $1}'", tmpPath)
-		tmpFileChecksum, err := sshCtx.ExecuteCommand(cmd)
-		if err != nil {
-			return fmt.Errorf("failed to execute checksum command : %v", err)
-		}
-
-		if strings.TrimSpace(tmpFileChecksum) == strings.TrimSpace(checksum) {
-			cmd := fmt.Sprintf("cp -f %s %s", tmpPath, path)
-			_, err := sshCtx.ExecuteCommand(cmd)
-			if err != nil {
-				return fmt.Errorf("failed to overwrite file : %v", err)
-			}
-
-			cmd = fmt.Sprintf("rm -f %s", tmpPath)
-			_, err = sshCtx.ExecuteCommand(cmd)
-			if err != nil {
-				return fmt.Errorf("failed to remove file : %v", err)
-			}
+	if !isLastChunk {
+		return "", nil
+	}
+	version, err := sshCtx.CommitUpload(id, CommitOptions{Checksum: checksum, BaseVersion: baseVersion})
+	if err != nil {
+		sshCtx.AbortUpload(id)
+		return "", err
+	}
+	return version, nil
+}
 
-		} else {
-			return fmt.Errorf("failed to write file (missmatch checksum) : %v", err)
-		}
+// SFTP로 파일 내용 복사 (대상 파일이 있으면 덮어씀)
+func (sshCtx *SSHContext) copyFile(src, dst string) error {
+	in, err := sshCtx.SFTPClient.Open(src)
+	if err != nil {
+		return err
 	}
+	defer in.Close()
 
-	return nil
+	out, err := sshCtx.SFTPClient.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
 }
 
 // 특정 경로의 파일 목록을 반환
@@ -222,39 +333,82 @@ func (sshCtx *SSHContext) GetFileList(root string) ([]FileInfo, error) {
 	}
 
 	for _, file := range files {
-		stat := file.Sys().(*sftp.FileStat)
-		ownerName, groupName := sshCtx.getOwnerGroupName(stat.UID, stat.GID)
-		perm := file.Mode().Perm().String()
-
-		fileInfo := FileInfo{
-			Name:  file.Name(),
-			IsDir: file.IsDir(),
-			Owner: ownerName,
-			Group: groupName,
-			Perm:  perm,
-			Size:  file.Size(),
-		}
-		filesList = append(filesList, fileInfo)
+		filesList = append(filesList, sshCtx.toFileInfo(path.Join(root, file.Name()), file))
 	}
 
 	return filesList, nil
 }
 
-// 파일 추가
+// 파일 정보 조회 (심볼릭 링크는 링크 자체의 정보)
+func (sshCtx *SSHContext) Stat(p string) (FileInfo, error) {
+	file, err := sshCtx.SFTPClient.Lstat(p)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return sshCtx.toFileInfo(p, file), nil
+}
+
+// p는 링크 대상을 확인할 때 사용하는 전체 경로
+func (sshCtx *SSHContext) toFileInfo(p string, file os.FileInfo) FileInfo {
+	var ownerName, groupName string
+	if stat, ok := file.Sys().(*sftp.FileStat); ok {
+		ownerName, groupName = sshCtx.getOwnerGroupName(stat.UID, stat.GID)
+	}
+
+	mode := file.Mode()
+	info := FileInfo{
+		Name:  file.Name(),
+		IsDir: file.IsDir(),
+		Owner: ownerName,
+		Group: groupName,
+		Perm:  mode.Perm().String(),
+		Size:  file.Size(),
+
+		Type:       fileTypeOf(mode),
+		Mode:       octalMode(mode),
+		Setuid:     mode&os.ModeSetuid != 0,
+		Setgid:     mode&os.ModeSetgid != 0,
+		Sticky:     mode&os.ModeSticky != 0,
+		Hidden:     strings.HasPrefix(file.Name(), ".") && file.Name() != "." && file.Name() != "..",
+		ModTime:    file.ModTime(),
+		AccessTime: accessTime(file),
+	}
+
+	switch info.Type {
+	case FileTypeFile:
+		info.MIME = guessMIME(info.Name)
+	case FileTypeSymlink:
+		sshCtx.resolveLinkInfo(p, &info)
+	}
+	return info
+}
+
+// 파일 추가 (이미 있으면 수정 시각만 갱신)
 func (sshCtx *SSHContext) AddFile(path string) error {
-	if _, err := sshCtx.ExecuteCommand(fmt.Sprintf("touch %s", path)); err != nil {
+	file, err := sshCtx.SFTPClient.OpenFile(path, os.O_WRONLY|os.O_CREATE)
+	if err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
 		return err
 	}
 
-	return nil
+	now := time.Now()
+	return sshCtx.SFTPClient.Chtimes(path, now, now)
 }
 
-// 파일 삭제
+// 파일 삭제 (파일이 없으면 무시)
 func (sshCtx *SSHContext) RemoveFile(path string) error {
-	if _, err := sshCtx.ExecuteCommand(fmt.Sprintf("rm -f %s", path)); err != nil {
+	info, err := sshCtx.SFTPClient.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
 		return err
 	}
-	return nil
+	if info.IsDir() {
+		return errors.New("is a directory: " + path)
+	}
+	return sshCtx.SFTPClient.Remove(path)
 }
 
 // 특정 사용자가 속한 그룹 목록 조회
@@ -277,3 +431,8 @@ func (sshCtx *SSHContext) CheckWritePermission(path string) bool {
 	// 파일을 열 수 있으면 쓰기 권한이 있음
 	return true
 }
+
+// 셸 명령 인자로 사용할 수 있도록 작은따옴표로 감쌈
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
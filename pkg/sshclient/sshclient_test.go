@@ -0,0 +1,270 @@
+package sshclient
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// 셸과 SFTP에서 특별한 의미가 있는 파일 이름
+var hostileNames = []string{
+	"with space",
+	"it's",
+	`double"quote`,
+	"$(touch pwned)",
+	"`touch pwned`",
+	"semi;touch pwned",
+	"-rf",
+	"--help",
+	"new\nline",
+	"tab\there",
+	"glob*?[a]",
+	"back\\slash",
+}
+
+// 명령 주입이 일어나면 생기는 파일
+const pwnedName = "pwned"
+
+// 테스트용 SSH 서버에 연결된 SSHContext (exec 요청은 workDir에서 /bin/sh -c로 실행)
+func newTestContext(t *testing.T, workDir string) *SSHContext {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestConn(conn, config, workDir)
+		}
+	}()
+
+	client, err := ssh.Dial("tcp", listener.Addr().String(), &ssh.ClientConfig{
+		User:            "test",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sftpClient, err := sftp.NewClient(client)
+	if err != nil {
+		client.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sftpClient.Close()
+		client.Close()
+	})
+
+	sshCtx := NewSSHContext()
+	sshCtx.Client = client
+	sshCtx.SFTPClient = sftpClient
+	return sshCtx
+}
+
+func serveTestConn(conn net.Conn, config *ssh.ServerConfig, workDir string) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChan := range chans {
+		if newChan.ChannelType() != "session" {
+			newChan.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		ch, requests, err := newChan.Accept()
+		if err != nil {
+			continue
+		}
+		go serveTestSession(ch, requests, workDir)
+	}
+}
+
+func serveTestSession(ch ssh.Channel, requests <-chan *ssh.Request, workDir string) {
+	defer ch.Close()
+
+	for req := range requests {
+		var payload struct{ Value string }
+		if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+			req.Reply(false, nil)
+			continue
+		}
+
+		switch {
+		case req.Type == "subsystem" && payload.Value == "sftp":
+			req.Reply(true, nil)
+			if server, err := sftp.NewServer(ch); err == nil {
+				server.Serve()
+			}
+			return
+		case req.Type == "exec":
+			req.Reply(true, nil)
+			cmd := exec.Command("/bin/sh", "-c", payload.Value)
+			cmd.Dir = workDir
+			cmd.Stdout = ch
+			cmd.Stderr = ch.Stderr()
+
+			var status uint32
+			if err := cmd.Run(); err != nil {
+				status = 1
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					status = uint32(exitErr.ExitCode())
+				}
+			}
+			ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+			return
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// dir 아래 (하위 디렉토리 포함) 모든 경로를 dir 기준 상대 경로로 반환
+func listTree(t *testing.T, dir string) map[string]bool {
+	t.Helper()
+	paths := make(map[string]bool)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if rel, _ := filepath.Rel(dir, p); rel != "." {
+			paths[rel] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
+
+// 명령 주입으로 생긴 파일이 없는지 확인
+func assertNotPwned(t *testing.T, dirs ...string) {
+	t.Helper()
+	for _, dir := range dirs {
+		for p := range listTree(t, dir) {
+			if filepath.Base(p) == pwnedName {
+				t.Errorf("unexpected file created: %s", filepath.Join(dir, p))
+			}
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	work := t.TempDir()
+	sshCtx := newTestContext(t, work)
+
+	for _, name := range hostileNames {
+		out, err := sshCtx.ExecuteCommand("printf %s " + shellQuote(name))
+		if err != nil {
+			t.Fatalf("printf %q: %v", name, err)
+		}
+		if out != name {
+			t.Errorf("shellQuote(%q) round trip = %q", name, out)
+		}
+	}
+	assertNotPwned(t, work)
+}
+
+func TestAddRemoveFileHostileNames(t *testing.T) {
+	work, target := t.TempDir(), t.TempDir()
+	sshCtx := newTestContext(t, work)
+
+	for _, name := range hostileNames {
+		p := filepath.Join(target, name)
+		if err := sshCtx.AddFile(p); err != nil {
+			t.Fatalf("AddFile(%q): %v", name, err)
+		}
+		if _, err := os.Stat(p); err != nil {
+			t.Fatalf("AddFile(%q) did not create the file: %v", name, err)
+		}
+	}
+
+	created := listTree(t, target)
+	if len(created) != len(hostileNames) {
+		t.Errorf("created %d entries, want %d: %v", len(created), len(hostileNames), created)
+	}
+	for _, name := range hostileNames {
+		if !created[name] {
+			t.Errorf("missing %q in target", name)
+		}
+	}
+
+	for _, name := range hostileNames {
+		if err := sshCtx.RemoveFile(filepath.Join(target, name)); err != nil {
+			t.Fatalf("RemoveFile(%q): %v", name, err)
+		}
+	}
+	if left := listTree(t, target); len(left) != 0 {
+		t.Errorf("entries left after RemoveFile: %v", left)
+	}
+	assertNotPwned(t, work, target)
+}
+
+func TestRemoveFileMissingAndDirectory(t *testing.T) {
+	target := t.TempDir()
+	sshCtx := newTestContext(t, t.TempDir())
+
+	if err := sshCtx.RemoveFile(filepath.Join(target, "missing")); err != nil {
+		t.Errorf("RemoveFile of missing file: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(target, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := sshCtx.RemoveFile(filepath.Join(target, "dir")); err == nil {
+		t.Error("RemoveFile of directory succeeded")
+	}
+}
+
+func TestLookupIDHostileNames(t *testing.T) {
+	if _, err := exec.LookPath("getent"); err != nil {
+		t.Skip("getent not available")
+	}
+	work := t.TempDir()
+	sshCtx := newTestContext(t, work)
+
+	if id, err := sshCtx.lookupID("passwd", "root"); err != nil || id != 0 {
+		t.Errorf("lookupID(root) = %d, %v", id, err)
+	}
+	if id, err := sshCtx.lookupID("passwd", "1234"); err != nil || id != 1234 {
+		t.Errorf("lookupID(1234) = %d, %v", id, err)
+	}
+
+	for _, name := range hostileNames {
+		if id, err := sshCtx.lookupID("passwd", name); err == nil {
+			t.Errorf("lookupID(%q) = %d, want error", name, id)
+		}
+		if id, err := sshCtx.lookupID("group", name); err == nil {
+			t.Errorf("lookupID(group, %q) = %d, want error", name, id)
+		}
+	}
+	assertNotPwned(t, work)
+}
@@ -0,0 +1,191 @@
+package sshclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+
+	"sshbck/pkg/queue"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	readBufferSize  = 8 * 1024 // 터미널 출력 읽기 단위
+	outputQueueSize = 64       // 전송 대기 중인 터미널 출력 청크 최대 개수
+)
+
+// 기본 터미널 채널 ID (채널을 지정하지 않은 요청에 사용)
+const DefaultTerminalID = "default"
+
+// 터미널 입출력 기록기 (호출자를 막지 않아야 함)
+type Recorder interface {
+	Output(data []byte)
+	Input(data []byte)
+	Resize(cols, rows int)
+	Close() error
+}
+
+// 하나의 SSH 연결 위에서 열린 PTY 채널
+type Terminal struct {
+	ID      string
+	Cols    int
+	Rows    int
+	Session *ssh.Session
+	Stdin   io.WriteCloser
+	Stdout  io.Reader
+	Queue   *queue.Queue
+
+	// 설정 시 입출력과 크기 변경을 기록 (채널 목록에 등록하기 전에 OpenTerminal에서 설정)
+	Recorder Recorder
+}
+
+// 새 PTY 채널을 열고 셸 시작 (rec가 nil이 아니면 처음부터 기록하며, 실패해도 rec는 닫지 않음)
+func (sshCtx *SSHContext) OpenTerminal(id string, cols, rows int, rec Recorder) (*Terminal, error) {
+	if sshCtx.Client == nil {
+		return nil, errors.New("ssh client is not connected")
+	}
+
+	sshCtx.termMutex.Lock()
+	if _, exists := sshCtx.terminals[id]; exists {
+		sshCtx.termMutex.Unlock()
+		return nil, errors.New("terminal already exists: " + id)
+	}
+	// 동시에 같은 ID로 여는 것을 막기 위해 자리 예약
+	sshCtx.terminals[id] = nil
+	sshCtx.termMutex.Unlock()
+
+	term, err := sshCtx.newTerminal(id, cols, rows, rec)
+
+	sshCtx.termMutex.Lock()
+	if err != nil {
+		delete(sshCtx.terminals, id)
+	} else {
+		sshCtx.terminals[id] = term
+	}
+	sshCtx.termMutex.Unlock()
+
+	return term, err
+}
+
+func (sshCtx *SSHContext) newTerminal(id string, cols, rows int, rec Recorder) (*Terminal, error) {
+	session, err := sshCtx.Client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	term := &Terminal{
+		ID:      id,
+		Cols:    cols,
+		Rows:    rows,
+		Session: session,
+		Queue:   queue.NewQueue(outputQueueSize),
+
+		Recorder: rec,
+	}
+
+	if err := session.RequestPty("xterm", rows, cols, ssh.TerminalModes{}); err != nil {
+		session.Close()
+		return nil, errors.New("pty request error: " + err.Error())
+	}
+	if term.Stdin, err = session.StdinPipe(); err != nil {
+		session.Close()
+		return nil, err
+	}
+	if term.Stdout, err = session.StdoutPipe(); err != nil {
+		session.Close()
+		return nil, err
+	}
+	if err := session.Shell(); err != nil {
+		session.Close()
+		return nil, errors.New("shell start error: " + err.Error())
+	}
+
+	return term, nil
+}
+
+// ID로 PTY 채널 조회
+func (sshCtx *SSHContext) Terminal(id string) (*Terminal, error) {
+	sshCtx.termMutex.Lock()
+	defer sshCtx.termMutex.Unlock()
+
+	term := sshCtx.terminals[id]
+	if term == nil {
+		return nil, errors.New("terminal not found: " + id)
+	}
+	return term, nil
+}
+
+// PTY 채널 목록에서 제거하고 남은 채널 수 반환
+func (sshCtx *SSHContext) RemoveTerminal(id string) int {
+	sshCtx.termMutex.Lock()
+	defer sshCtx.termMutex.Unlock()
+
+	delete(sshCtx.terminals, id)
+	return len(sshCtx.terminals)
+}
+
+// Stdout를 지속적으로 읽고 출력 내용을 Queue에 추가 (Queue가 가득 차면 읽기를 멈춤)
+func (t *Terminal) Read(ctx context.Context) {
+	defer t.Queue.Close()
+
+	buf := make([]byte, readBufferSize)
+	for {
+		n, err := t.Stdout.Read(buf)
+		if n > 0 {
+			chunk := make([]byte, n)
+			copy(chunk, buf[:n])
+			if t.Recorder != nil {
+				t.Recorder.Output(chunk)
+			}
+			if pushErr := t.Queue.Push(ctx, chunk); pushErr != nil {
+				log.Println("Read context done")
+				return
+			}
+		}
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			log.Println("I/O read error:", err)
+			return
+		}
+	}
+}
+
+// 터미널 입력 전송
+func (t *Terminal) Write(data []byte) error {
+	if t.Recorder != nil {
+		t.Recorder.Input(data)
+	}
+	_, err := t.Stdin.Write(data)
+	return err
+}
+
+// 터미널 크기 변경
+func (t *Terminal) Resize(cols, rows int) error {
+	if t.Recorder != nil {
+		t.Recorder.Resize(cols, rows)
+	}
+	return t.Session.WindowChange(rows, cols)
+}
+
+// 셸 종료 대기 후 종료 코드 반환 (종료 코드를 알 수 없으면 -1)
+func (t *Terminal) Wait() int {
+	err := t.Session.Wait()
+
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		return exitErr.ExitStatus()
+	default:
+		return -1
+	}
+}
+
+// PTY 채널 닫기
+func (t *Terminal) Close() error {
+	return t.Session.Close()
+}
@@ -0,0 +1,441 @@
+package sshclient
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/sftp"
+)
+
+var ErrUploadNotFound = errors.New("upload not found")
+
+// 업로드 임시 파일 이름 (.<파일 이름>.sshbck-<id>.tmp)
+const (
+	stagingInfix  = ".sshbck-"
+	stagingSuffix = ".tmp"
+)
+
+// 심볼릭 링크를 따라가는 최대 횟수
+const maxSymlinks = 40
+
+// 새로 만드는 파일의 권한
+const defaultFileMode os.FileMode = 0644
+
+// 수신한 바이트 구간 [Start, End)
+type Range struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// 오프셋 단위로 이어서 받을 수 있는 업로드
+type Upload struct {
+	ID   string
+	Path string // 최종 저장 경로
+	Size int64  // 전체 크기 (음수면 알 수 없음)
+
+	Algorithm ChecksumAlgorithm // 커밋 시 검증할 체크섬 알고리즘
+
+	mu           sync.Mutex
+	tmpPath      string
+	copyOnCommit bool // 임시 파일이 대상과 다른 디렉토리에 있으면 rename 대신 복사
+	file         *sftp.File
+	ranges       []Range // 정렬 및 병합된 수신 구간
+
+	// 순서대로 받은 구간은 쓰는 동안 바로 해시에 반영
+	digest hash.Hash // 요청된 알고리즘
+	sha    hash.Hash // 파일 버전용 SHA-256 (요청된 알고리즘이 SHA-256이면 digest와 같음)
+	hashed int64     // 처음부터 해시에 반영한 바이트 수
+}
+
+// 업로드 커밋 옵션
+type CommitOptions struct {
+	Checksum    string // Upload.Algorithm 으로 계산한 hex 체크섬
+	BaseVersion string // 지정하면 그 사이 원격 파일이 바뀐 경우 ConflictError
+	Verify      bool   // 저장 전에 임시 파일을 다시 읽어 체크섬 재확인
+}
+
+// 업로드 세션 목록 (SSH 연결 단위로 유지되므로 WebSocket 재연결 후에도 이어서 사용 가능)
+type uploadRegistry struct {
+	mu      sync.Mutex
+	uploads map[string]*Upload
+}
+
+// 업로드 시작
+func (sshCtx *SSHContext) StartUpload(path string, size int64, alg ChecksumAlgorithm) (*Upload, error) {
+	id, err := newUploadID()
+	if err != nil {
+		return nil, err
+	}
+	return sshCtx.startUpload(id, path, size, alg)
+}
+
+func (sshCtx *SSHContext) startUpload(id, target string, size int64, alg ChecksumAlgorithm) (*Upload, error) {
+	if alg == "" {
+		alg = ChecksumSHA256
+	}
+	if _, err := NewHash(alg); err != nil {
+		return nil, err
+	}
+
+	// 심볼릭 링크는 링크를 교체하지 않도록 실제 파일에 저장
+	target, err := sshCtx.resolveSymlink(target)
+	if err != nil {
+		return nil, errors.New("failed to resolve symlink: " + err.Error())
+	}
+
+	suffix, err := newUploadID()
+	if err != nil {
+		return nil, err
+	}
+	upload := &Upload{
+		ID:        id,
+		Path:      target,
+		Size:      size,
+		Algorithm: alg,
+		// 원자적으로 교체할 수 있도록 대상과 같은 디렉토리에 고유한 이름으로 저장
+		tmpPath: path.Join(path.Dir(target), "."+path.Base(target)+stagingInfix+suffix+stagingSuffix),
+	}
+
+	file, err := sshCtx.SFTPClient.OpenFile(upload.tmpPath, os.O_RDWR|os.O_CREATE|os.O_EXCL)
+	if errors.Is(err, os.ErrPermission) {
+		// 디렉토리에 쓸 수 없으면 /tmp에 저장했다가 대상 파일에 덮어씀
+		upload.tmpPath = "/tmp/" + stagingInfix[1:] + suffix + stagingSuffix
+		upload.copyOnCommit = true
+		file, err = sshCtx.SFTPClient.OpenFile(upload.tmpPath, os.O_RDWR|os.O_CREATE|os.O_EXCL)
+	}
+	if err != nil {
+		return nil, errors.New("failed to create staging file: " + err.Error())
+	}
+	upload.file = file
+	upload.resetHash()
+
+	sshCtx.uploads.mu.Lock()
+	if old := sshCtx.uploads.uploads[id]; old != nil {
+		// 같은 ID로 다시 시작하면 이전 업로드는 버림
+		defer sshCtx.discardUpload(old)
+	}
+	sshCtx.uploads.uploads[id] = upload
+	sshCtx.uploads.mu.Unlock()
+	return upload, nil
+}
+
+// ID로 업로드 조회
+func (sshCtx *SSHContext) Upload(id string) (*Upload, error) {
+	sshCtx.uploads.mu.Lock()
+	defer sshCtx.uploads.mu.Unlock()
+
+	upload := sshCtx.uploads.uploads[id]
+	if upload == nil {
+		return nil, ErrUploadNotFound
+	}
+	return upload, nil
+}
+
+// 수신 구간과 체크섬 검사 후 최종 경로로 저장하고 저장된 파일의 버전 반환
+func (sshCtx *SSHContext) CommitUpload(id string, opts CommitOptions) (string, error) {
+	upload, err := sshCtx.Upload(id)
+	if err != nil {
+		return "", err
+	}
+
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	if err := sshCtx.sealUpload(upload, opts.Checksum, opts.Verify); err != nil {
+		return "", err
+	}
+
+	// 파일을 읽은 뒤 다른 곳에서 변경되었으면 덮어쓰지 않음
+	if err := sshCtx.checkVersion(upload.Path, opts.BaseVersion); err != nil {
+		return "", sshCtx.reopen(upload, err)
+	}
+
+	if upload.copyOnCommit {
+		// 기존 파일에 덮어쓰므로 권한과 소유자는 그대로 유지됨
+		if err := sshCtx.copyFile(upload.tmpPath, upload.Path); err != nil {
+			return "", sshCtx.reopen(upload, errors.New("failed to overwrite file: "+err.Error()))
+		}
+		if err := sshCtx.SFTPClient.Remove(upload.tmpPath); err != nil {
+			log.Println("failed to remove staging file:", err)
+		}
+	} else if err := sshCtx.replaceFile(upload.tmpPath, upload.Path); err != nil {
+		return "", sshCtx.reopen(upload, errors.New("failed to replace file: "+err.Error()))
+	}
+
+	sshCtx.removeUpload(id)
+
+	info, err := sshCtx.SFTPClient.Stat(upload.Path)
+	if err != nil {
+		return "", err
+	}
+	return FileVersion(info.ModTime(), info.Size(), hex.EncodeToString(upload.sha.Sum(nil))), nil
+}
+
+// 수신을 마치고 임시 파일을 닫은 뒤 체크섬 확인 (upload.mu를 잡은 상태에서 호출)
+//
+// 검증에 실패해도 다시 커밋하거나 취소할 수 있도록 임시 파일을 다시 열어 둔다.
+func (sshCtx *SSHContext) sealUpload(upload *Upload, expected string, verify bool) error {
+	if upload.file == nil {
+		return errors.New("staging file is not available")
+	}
+	if !upload.complete() {
+		return errors.New("upload is incomplete")
+	}
+	if err := upload.file.Close(); err != nil {
+		return errors.New("failed to close staging file: " + err.Error())
+	}
+	upload.file = nil
+
+	checksum, err := sshCtx.finishHash(upload)
+	if err != nil {
+		return sshCtx.reopen(upload, errors.New("failed to compute checksum: "+err.Error()))
+	}
+	if !strings.EqualFold(checksum, strings.TrimSpace(expected)) {
+		return sshCtx.reopen(upload, errors.New("checksum mismatch"))
+	}
+	if verify {
+		stored, err := sshCtx.Checksum(upload.tmpPath, upload.Algorithm)
+		if err != nil {
+			return sshCtx.reopen(upload, errors.New("failed to verify checksum: "+err.Error()))
+		}
+		if stored != checksum {
+			return sshCtx.reopen(upload, errors.New("checksum mismatch on stored file"))
+		}
+	}
+	return nil
+}
+
+// 진행 중인 업로드를 모두 취소하고 임시 파일 삭제 (세션 종료 시 호출)
+func (sshCtx *SSHContext) CloseUploads() {
+	sshCtx.uploads.mu.Lock()
+	uploads := sshCtx.uploads.uploads
+	sshCtx.uploads.uploads = make(map[string]*Upload)
+	sshCtx.uploads.mu.Unlock()
+
+	for _, upload := range uploads {
+		if err := sshCtx.discardUpload(upload); err != nil {
+			log.Println("failed to remove staging file:", err)
+		}
+	}
+}
+
+// 심볼릭 링크가 가리키는 경로 (링크가 아니거나 없으면 그대로)
+func (sshCtx *SSHContext) resolveSymlink(p string) (string, error) {
+	for i := 0; i < maxSymlinks; i++ {
+		info, err := sshCtx.SFTPClient.Lstat(p)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			return p, nil
+		}
+		link, err := sshCtx.SFTPClient.ReadLink(p)
+		if err != nil {
+			return "", err
+		}
+		if !path.IsAbs(link) {
+			link = path.Join(path.Dir(p), link)
+		}
+		p = link
+	}
+	return "", errors.New("too many levels of symbolic links")
+}
+
+// 임시 파일에 기존 파일의 권한과 소유자를 적용한 뒤 원자적으로 교체
+func (sshCtx *SSHContext) replaceFile(tmpPath, target string) error {
+	info, err := sshCtx.SFTPClient.Lstat(target)
+	switch {
+	case err == nil:
+		if err := sshCtx.SFTPClient.Chmod(tmpPath, info.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+			return err
+		}
+		if stat, ok := info.Sys().(*sftp.FileStat); ok {
+			// 다른 사용자 소유 파일은 권한이 없으면 소유자를 유지할 수 없음
+			if err := sshCtx.SFTPClient.Chown(tmpPath, int(stat.UID), int(stat.GID)); err != nil {
+				log.Printf("failed to preserve owner of %s: %v", target, err)
+			}
+		}
+	case errors.Is(err, os.ErrNotExist):
+		if err := sshCtx.SFTPClient.Chmod(tmpPath, defaultFileMode); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+	return sshCtx.posixRename(tmpPath, target)
+}
+
+// 업로드 취소 및 임시 파일 삭제
+func (sshCtx *SSHContext) AbortUpload(id string) error {
+	upload, err := sshCtx.Upload(id)
+	if err != nil {
+		return err
+	}
+	sshCtx.removeUpload(id)
+	return sshCtx.discardUpload(upload)
+}
+
+func (sshCtx *SSHContext) removeUpload(id string) {
+	sshCtx.uploads.mu.Lock()
+	delete(sshCtx.uploads.uploads, id)
+	sshCtx.uploads.mu.Unlock()
+}
+
+func (sshCtx *SSHContext) discardUpload(upload *Upload) error {
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	if upload.file != nil {
+		upload.file.Close()
+		upload.file = nil
+	}
+	if err := sshCtx.SFTPClient.Remove(upload.tmpPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// 커밋 실패 후 이어서 쓸 수 있도록 임시 파일을 다시 열고 원래 오류 반환
+func (sshCtx *SSHContext) reopen(upload *Upload, cause error) error {
+	file, err := sshCtx.SFTPClient.OpenFile(upload.tmpPath, os.O_RDWR)
+	if err != nil {
+		return errors.New(cause.Error() + " (staging file lost: " + err.Error() + ")")
+	}
+	upload.file = file
+	return cause
+}
+
+// offset 위치에 데이터 쓰기 (같은 구간을 다시 받아도 안전)
+func (u *Upload) WriteAt(offset int64, data []byte) error {
+	if offset < 0 {
+		return errors.New("invalid offset")
+	}
+	end := offset + int64(len(data))
+	if u.Size >= 0 && end > u.Size {
+		return errors.New("chunk exceeds upload size")
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.file == nil {
+		return errors.New("upload is being committed")
+	}
+	if _, err := u.file.WriteAt(data, offset); err != nil {
+		return errors.New("failed to write chunk: " + err.Error())
+	}
+	if len(data) > 0 {
+		u.ranges = mergeRange(u.ranges, Range{Start: offset, End: end})
+	}
+
+	switch {
+	case offset == u.hashed:
+		u.writeHash(data)
+		u.hashed = end
+	case offset < u.hashed:
+		// 이미 해시에 반영한 구간을 다시 받으면 내용이 바뀌었을 수 있으므로 커밋 때 처음부터 다시 계산
+		u.resetHash()
+	}
+	// offset > u.hashed 인 구간은 커밋 때 임시 파일에서 읽어 반영
+	return nil
+}
+
+func (u *Upload) resetHash() {
+	u.digest, _ = NewHash(u.Algorithm)
+	u.sha = u.digest
+	if u.Algorithm != ChecksumSHA256 {
+		u.sha, _ = NewHash(ChecksumSHA256)
+	}
+	u.hashed = 0
+}
+
+func (u *Upload) writeHash(data []byte) {
+	u.digest.Write(data)
+	if u.sha != u.digest {
+		u.sha.Write(data)
+	}
+}
+
+// 아직 해시에 반영하지 않은 나머지를 임시 파일에서 읽어 체크섬 계산 (u.mu를 잡은 상태에서 호출)
+func (sshCtx *SSHContext) finishHash(u *Upload) (string, error) {
+	var total int64
+	if len(u.ranges) > 0 {
+		total = u.ranges[0].End
+	}
+	if u.hashed < total {
+		if err := sshCtx.hashFile(u.tmpPath, u.hashed, writerFunc(u.writeHash)); err != nil {
+			u.resetHash()
+			return "", err
+		}
+		u.hashed = total
+	}
+	return hex.EncodeToString(u.digest.Sum(nil)), nil
+}
+
+type writerFunc func(p []byte)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	f(p)
+	return len(p), nil
+}
+
+// 수신한 구간 목록
+func (u *Upload) Ranges() []Range {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]Range(nil), u.ranges...)
+}
+
+// 이어서 받을 위치 (처음부터 연속으로 받은 바이트 수)
+func (u *Upload) Received() int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if len(u.ranges) == 0 || u.ranges[0].Start != 0 {
+		return 0
+	}
+	return u.ranges[0].End
+}
+
+// 전체 구간을 빠짐없이 받았는지 확인 (u.mu를 잡은 상태에서 호출)
+func (u *Upload) complete() bool {
+	if len(u.ranges) == 0 {
+		return u.Size <= 0
+	}
+	if len(u.ranges) > 1 || u.ranges[0].Start != 0 {
+		return false
+	}
+	return u.Size < 0 || u.ranges[0].End == u.Size
+}
+
+// 구간을 추가하고 겹치거나 맞닿은 구간은 병합
+func mergeRange(ranges []Range, r Range) []Range {
+	ranges = append(ranges, r)
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+
+	merged := ranges[:1]
+	for _, next := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if next.Start <= last.End {
+			if next.End > last.End {
+				last.End = next.End
+			}
+			continue
+		}
+		merged = append(merged, next)
+	}
+	return merged
+}
+
+func newUploadID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,69 @@
+package sshclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+var ErrConflict = errors.New("file was modified since it was read")
+
+// 파일을 읽은 뒤 다른 곳에서 변경되어 저장이 거부됨
+type ConflictError struct {
+	Path    string
+	Version string // 현재 원격 파일 버전 (삭제되었으면 빈 문자열)
+}
+
+func (e *ConflictError) Error() string {
+	return ErrConflict.Error() + ": " + e.Path
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// 파일 버전 토큰 (<mtime>-<size>-<sha256>)
+func FileVersion(modTime time.Time, size int64, checksum string) string {
+	return fmt.Sprintf("%d-%d-%s", modTime.Unix(), size, strings.ToLower(checksum))
+}
+
+// 현재 원격 파일 버전 (파일이 없으면 빈 문자열)
+func (sshCtx *SSHContext) FileVersion(p string) (string, error) {
+	file, err := sshCtx.SFTPClient.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return FileVersion(info.ModTime(), info.Size(), hex.EncodeToString(hash.Sum(nil))), nil
+}
+
+// 원격 파일이 base 버전 이후 변경되었으면 ConflictError 반환 (base가 비어 있으면 검사하지 않음)
+func (sshCtx *SSHContext) checkVersion(p, base string) error {
+	if base == "" {
+		return nil
+	}
+	current, err := sshCtx.FileVersion(p)
+	if err != nil {
+		return err
+	}
+	if current != base {
+		return &ConflictError{Path: p, Version: current}
+	}
+	return nil
+}
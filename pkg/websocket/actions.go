@@ -1,128 +1,299 @@
 package websocket
 
 import (
-	"context"
 	"errors"
+	"fmt"
 	"log"
+	"net"
+	"sshbck/pkg/recorder"
 	"sshbck/pkg/sshclient"
-	"time"
 
-	"github.com/gorilla/websocket"
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 )
 
+// 터미널 출력 프레임 최대 크기
+const maxTerminalFrame = 32 * 1024
+
 // 연결 처리
-func handleConnect(wsCtx *WSHandlerContext, requestData map[string]interface{}) error {
-	// 클라이언트로 큐의 터미널 메시지 전송
-	go func(ctx context.Context) {
+func handleConnect(wsCtx *WSHandlerContext, req connectRequest) error {
+	if !wsCtx.serverCredentials && req.usesServerCredentials() {
+		return errors.New("keyRef and useAgent require an authorized connection")
+	}
+	if !wsCtx.beginConnect() {
+		return errors.New("already connected or connecting")
+	}
+
+	// SSH 및 SFTP 연결 설정
+	wsCtx.goSafe("ssh setup", func() {
+		if err := setupSSHSFTP(wsCtx, req); err != nil {
+			log.Println("SSH setup error:", err)
+			wsCtx.abortConnect()
+			state := connectionStateResponse{State: ConnStateFailed}
+			var hopErr *sshclient.HopError
+			if errors.As(err, &hopErr) {
+				state.Hop = &hopErr.Hop
+				state.Address = hopErr.Address
+			}
+			sendConnectionState(wsCtx, StatusFailed, state, err.Error())
+		}
+	})
+
+	return nil
+}
+
+// 연결 상태 메시지 전송
+func sendConnectionState(wsCtx *WSHandlerContext, status Status, state connectionStateResponse, errMsg string) {
+	msg, err := toJSON(state)
+	if err != nil {
+		log.Println("JSON marshal error:", err)
+		return
+	}
+	wsCtx.safeWS.WriteJSON(wsCtx.message(ActionConnect, msg, status, errMsg))
+}
+
+// PTY 채널 출력 전송 및 셸 종료 처리
+func runTerminal(wsCtx *WSHandlerContext, term *sshclient.Terminal) {
+	drained := make(chan struct{})
+	out := wsCtx.newTerminalOutput(term.ID)
+
+	wsCtx.goSafe("terminal read", func() {
+		term.Read(wsCtx.ctx)
+	})
+
+	// 클라이언트로 큐의 터미널 메시지 전송 (작은 출력은 하나의 프레임으로 합침)
+	wsCtx.goSafe("terminal output", func() {
+		defer close(drained)
 		for {
-			select {
-			case <-ctx.Done():
+			frame, err := term.Queue.Pop(wsCtx.ctx, maxTerminalFrame)
+			if err != nil {
 				return
-			default:
-				if wsCtx.ssh.Queue.Len() > 0 {
-					data := createMessage(string(ActionTerminal), wsCtx.ssh.Queue.Pop().([]byte), StatusSuccess, "")
-					if err := wsCtx.safeWS.WriteMessage(websocket.TextMessage, data); err != nil {
-						log.Println("WebSocket write error:", err)
-						return
-					}
-				} else {
-					time.Sleep(100 * time.Millisecond)
-				}
 			}
+			out.send(wsCtx, term.ID, frame)
 		}
-	}(wsCtx.ctx)
+	})
 
-	// SSH 및 SFTP 연결 설정
-	go setupSSHSFTP(wsCtx, requestData)
+	wsCtx.goSafe("terminal wait", func() {
+		status := term.Wait()
+		<-drained
+		if term.Recorder != nil {
+			term.Recorder.Close()
+		}
+
+		wsCtx.removeTerminalOutput(term.ID)
+		remaining := wsCtx.ssh.RemoveTerminal(term.ID)
+		msg, _ := toJSON(terminalResponse{Channel: term.ID, ExitStatus: &status})
+		wsCtx.safeWS.WriteJSON(wsCtx.message(ActionCloseTerminal, msg, StatusSuccess, ""))
+
+		// 마지막 PTY 채널이 종료되면 종료 코드를 알리고 WebSocket 컨텍스트 종료
+		if remaining == 0 {
+			sendConnectionState(wsCtx, StatusSuccess, connectionStateResponse{
+				State:      ConnStateClosed,
+				ExitStatus: &status,
+			}, "")
+			wsCtx.cancel()
+		}
+	})
+}
+
+// 녹화 설정에 따라 녹화를 시작하고 PTY 채널 열기
+//
+// 채널이 목록에 등록된 뒤에는 다른 요청이 바로 입력과 리사이즈를 보낼 수 있으므로 녹화기는 미리 만들어 전달한다.
+func openTerminal(wsCtx *WSHandlerContext, id string, cols, rows int) (*sshclient.Terminal, error) {
+	if options.Recording.Dir == "" {
+		return wsCtx.ssh.OpenTerminal(id, cols, rows, nil)
+	}
+
+	rec, err := recorder.New(options.Recording, wsCtx.ssh.User, wsCtx.ssh.Address, id, cols, rows)
+	if err != nil {
+		log.Println("recording start error:", err)
+		return wsCtx.ssh.OpenTerminal(id, cols, rows, nil)
+	}
+	term, err := wsCtx.ssh.OpenTerminal(id, cols, rows, rec)
+	if err != nil {
+		rec.Close()
+	}
+	return term, err
+}
+
+// PTY 채널 열기
+func handleOpenTerminal(wsCtx *WSHandlerContext, req openTerminalRequest) error {
+	term, err := openTerminal(wsCtx, req.Channel, req.Cols, req.Rows)
+	if err != nil {
+		return errors.New("terminal open error: " + err.Error())
+	}
+	runTerminal(wsCtx, term)
+
+	msg, err := toJSON(terminalResponse{Channel: term.ID})
+	if err != nil {
+		return errors.New("json marshal error: " + err.Error())
+	}
+	wsCtx.safeWS.WriteJSON(wsCtx.message(ActionOpenTerminal, msg, StatusSuccess, ""))
+	return nil
+}
 
+// PTY 채널 닫기 (종료 알림은 runTerminal에서 전송)
+func handleCloseTerminal(wsCtx *WSHandlerContext, req closeTerminalRequest) error {
+	term, err := wsCtx.ssh.Terminal(channelID(req.Channel))
+	if err != nil {
+		return err
+	}
+	if err := term.Close(); err != nil {
+		return errors.New("terminal close error: " + err.Error())
+	}
 	return nil
 }
 
 // 터미널 리사이즈
-func handleResize(wsCtx *WSHandlerContext, requestData map[string]interface{}) error {
-	if err := resizePTY(wsCtx.ssh.Session, requestData); err != nil {
+func handleResize(wsCtx *WSHandlerContext, req resizeRequest) error {
+	term, err := wsCtx.ssh.Terminal(channelID(req.Channel))
+	if err != nil {
+		return errors.New("resize error: " + err.Error())
+	}
+	if err := term.Resize(req.Cols, req.Rows); err != nil {
 		return errors.New("resize error: " + err.Error())
 	}
 
-	wsCtx.safeWS.WriteJSON(createMessage(string(ActionResize), nil, StatusSuccess, ""))
+	wsCtx.safeWS.WriteJSON(wsCtx.message(ActionResize, nil, StatusSuccess, ""))
 	return nil
 }
 
 // 터미널 메시지를 SSH 서버로 전송
-func handleTerminal(wsCtx *WSHandlerContext, requestData map[string]interface{}) error {
-	termMsg := requestData["data"].(string)
-	if wsCtx.ssh.Stdin == nil {
-		return errors.New("stdin is nil")
+func handleTerminal(wsCtx *WSHandlerContext, req terminalRequest) error {
+	term, err := wsCtx.ssh.Terminal(channelID(req.Channel))
+	if err != nil {
+		return err
 	}
-	if _, err := wsCtx.ssh.Stdin.Write([]byte(termMsg)); err != nil {
+	if err := term.Write([]byte(req.Data)); err != nil {
 		return errors.New("write error: " + err.Error())
 	}
 	return nil
 }
 
-func handleGetGroups(wsCtx *WSHandlerContext, requestData map[string]interface{}) error {
+// 채널을 지정하지 않으면 기본 채널 사용
+func channelID(channel string) string {
+	if channel == "" {
+		return sshclient.DefaultTerminalID
+	}
+	return channel
+}
+
+// 요청 ID로 시작한 작업 취소 (tailfile 등)
+func handleCancel(wsCtx *WSHandlerContext, req cancelRequest) error {
+	if !wsCtx.cancelTask(req.RequestID) {
+		return errors.New("task not found: " + req.RequestID)
+	}
+	wsCtx.safeWS.WriteJSON(wsCtx.message(ActionCancel, nil, StatusSuccess, ""))
+	return nil
+}
+
+func handleGetGroups(wsCtx *WSHandlerContext, req emptyRequest) error {
 	groups, err := wsCtx.ssh.GetGroups()
 	if err != nil {
 		return errors.New("group retrieval error: " + err.Error())
 	}
 
-	msg, err := toJSON(map[string]interface{}{"groups": groups})
+	msg, err := toJSON(groupsResponse{Groups: groups})
 	if err != nil {
 		return errors.New("json marshal error: " + err.Error())
 	}
 
-	wsCtx.safeWS.WriteJSON(createMessage(string(ActionGetGroups), msg, StatusSuccess, ""))
+	wsCtx.safeWS.WriteJSON(wsCtx.message(ActionGetGroups, msg, StatusSuccess, ""))
 	return nil
 }
 
-// terminal pty 리사이즈
-func resizePTY(session *ssh.Session, data map[string]interface{}) error {
-	cols := int(data["cols"].(float64))
-	rows := int(data["rows"].(float64))
+// 요청 데이터로 SSH 인증 설정 생성
+func newAuthConfig(wsCtx *WSHandlerContext, req hostRequest) *sshclient.AuthConfig {
+	auth := &sshclient.AuthConfig{
+		User:                req.Username,
+		Password:            req.Password,
+		PrivateKey:          []byte(req.PrivateKey),
+		Passphrase:          req.Passphrase,
+		Certificate:         []byte(req.Certificate),
+		KeyRef:              req.KeyRef,
+		KeyStore:            options.KeyStore,
+		KeyboardInteractive: newKeyboardInteractiveChallenge(wsCtx),
+	}
+	if req.UseAgent {
+		auth.AgentSocket = options.AgentSocket
+	}
+	for _, authType := range req.AuthOrder {
+		auth.Order = append(auth.Order, sshclient.AuthType(authType))
+	}
+	return auth
+}
+
+// 요청 데이터로 호스트 하나의 SSH 설정 생성
+func newHostConfig(wsCtx *WSHandlerContext, req hostRequest) sshclient.Config {
+	return sshclient.Config{
+		ServerConfig: &ssh.ClientConfig{
+			HostKeyCallback: hostKeyCallback(wsCtx, hostKeyPolicy(req.HostKeyPolicy)),
+		},
+		Auth:     newAuthConfig(wsCtx, req),
+		Protocol: "tcp",
+		Address:  net.JoinHostPort(req.Host, req.Port),
+	}
+}
 
-	return session.WindowChange(rows, cols)
+// 접속 단계에 해당하는 연결 상태
+func stageState(stage sshclient.Stage) string {
+	switch stage {
+	case sshclient.StageDialing:
+		return ConnStateDialing
+	case sshclient.StageAuthenticating:
+		return ConnStateAuthenticating
+	default:
+		return string(stage)
+	}
 }
 
 // SSH 및 SFTP 연결 설정
-func setupSSHSFTP(wsCtx *WSHandlerContext, config map[string]interface{}) error {
-	log.Println("setupSSHSFTP", config)
-	addr := config["host"].(string) + ":" + config["port"].(string)
-	cols := int(config["cols"].(float64))
-	rows := int(config["rows"].(float64))
-
-	serverConfig := &ssh.ClientConfig{
-		User: config["username"].(string),
-		Auth: []ssh.AuthMethod{
-			ssh.Password(config["password"].(string)),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+func setupSSHSFTP(wsCtx *WSHandlerContext, req connectRequest) (err error) {
+	sshConfig := newHostConfig(wsCtx, req.hostRequest)
+	for _, jumpHost := range req.JumpHosts {
+		sshConfig.JumpHosts = append(sshConfig.JumpHosts, newHostConfig(wsCtx, jumpHost))
 	}
-	sshConfig := sshclient.Config{
-		ServerConfig: serverConfig,
-		Protocol:     "tcp",
-		Address:      addr,
+
+	sshConfig.Notify = func(stage sshclient.Stage, hop int, address string) {
+		sendConnectionState(wsCtx, StatusInProgress, connectionStateResponse{
+			State:   stageState(stage),
+			Hop:     &hop,
+			Address: address,
+		}, "")
 	}
 
 	conn, err := sshConfig.NewConn()
 	if err != nil {
-		return errors.New("ssh connection error: " + err.Error())
+		return fmt.Errorf("ssh connection error: %w", err)
 	}
 	defer conn.Close()
 
-	session, err := sshConfig.NewSession(conn)
+	wsCtx.ssh.Client = conn
+	wsCtx.ssh.User = req.Username
+	wsCtx.ssh.Address = sshConfig.Address
+
+	var term *sshclient.Terminal
+	defer func() {
+		if err == nil {
+			return
+		}
+		// 설정 중 실패하면 열어 둔 터미널을 목록에서 빼고 다시 연결할 수 있도록 초기화
+		if term != nil {
+			wsCtx.ssh.RemoveTerminal(term.ID)
+			if term.Recorder != nil {
+				term.Recorder.Close()
+			}
+		}
+		wsCtx.ssh.Client = nil
+		wsCtx.ssh.SFTPClient = nil
+	}()
+
+	term, err = openTerminal(wsCtx, channelID(req.Channel), req.Cols, req.Rows)
 	if err != nil {
 		return errors.New("ssh session error: " + err.Error())
 	}
-	defer session.Close()
-
-	session.RequestPty("xterm", rows, cols, ssh.TerminalModes{})
-
-	wsCtx.ssh.Client = conn
-	wsCtx.ssh.Session = session
-	wsCtx.ssh.Stdin, _ = session.StdinPipe()
-	wsCtx.ssh.Stdout, _ = session.StdoutPipe()
+	defer term.Close()
+	sendConnectionState(wsCtx, StatusInProgress, connectionStateResponse{State: ConnStatePTYReady}, "")
 
 	// Set up SFTP client
 	wsCtx.ssh.SFTPClient, err = sftp.NewClient(conn)
@@ -130,10 +301,17 @@ func setupSSHSFTP(wsCtx *WSHandlerContext, config map[string]interface{}) error
 		return errors.New("sftp client setup error: " + err.Error())
 	}
 	defer wsCtx.ssh.SFTPClient.Close()
+	// 세션이 끝나면 완료되지 않은 업로드의 임시 파일 정리
+	defer wsCtx.ssh.CloseUploads()
 
-	go wsCtx.ssh.Read(wsCtx.ctx)
+	// WebSocket 연결이 끊겨도 이어서 사용할 수 있도록 세션 등록
+	token, err := wsCtx.registerSession()
+	if err != nil {
+		return errors.New("session token error: " + err.Error())
+	}
+	sendConnectionState(wsCtx, StatusSuccess, connectionStateResponse{State: ConnStateSFTPReady, ResumeToken: token}, "")
 
-	session.Shell()
+	runTerminal(wsCtx, term)
 
 	<-wsCtx.ctx.Done()
 	return nil
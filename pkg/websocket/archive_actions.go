@@ -0,0 +1,113 @@
+package websocket
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+
+	"sshbck/pkg/sshclient"
+)
+
+const (
+	maxArchiveSize   = 4 << 30   // 디렉토리 다운로드 원본 크기 합계 최대값
+	archiveChunkSize = 32 * 1024 // 압축 파일 전송 단위
+)
+
+// 디렉토리 다운로드 진행 상황
+type ArchiveProgress struct {
+	Current string `json:"current"` // 마지막으로 추가한 파일
+	Files   int    `json:"files"`
+	Bytes   int64  `json:"bytes"` // 원본 기준 누적 크기
+}
+
+// 압축 파일을 FileChunk 프레임으로 나누어 전송하는 Writer
+type chunkWriter struct {
+	wsCtx    *WSHandlerContext
+	action   Action
+	chunk    FileChunk
+	buf      []byte
+	hash     hash.Hash
+	progress ArchiveProgress
+}
+
+// 디렉토리를 tar.gz 또는 zip으로 압축하여 전송 (cancel 액션으로 중지 가능)
+func handleDownloadDir(wsCtx *WSHandlerContext, req downloadDirRequest) error {
+	ctx, done, err := wsCtx.optionalTask()
+	if err != nil {
+		return err
+	}
+
+	wsCtx.goSafe("download dir", func() {
+		defer done()
+
+		name := sshclient.ArchiveBaseName(req.Path) + "." + req.Format
+		w := &chunkWriter{
+			wsCtx:  wsCtx,
+			action: ActionDownloadDir,
+			chunk:  FileChunk{FileHash: generateUniqueHash(req.Path), Path: name},
+			hash:   sha256.New(),
+		}
+
+		err := wsCtx.ssh.WriteArchive(ctx, req.Path, sshclient.ArchiveFormat(req.Format), sshclient.ArchiveOptions{
+			Include: req.Include,
+			Exclude: req.Exclude,
+			MaxSize: req.MaxSize,
+			Progress: func(current string, files int, bytes int64) {
+				w.progress = ArchiveProgress{Current: current, Files: files, Bytes: bytes}
+			},
+		}, w)
+		if err == nil {
+			err = w.finish()
+		}
+		if err != nil {
+			log.Println("download dir error:", err)
+			if !errors.Is(err, errDetached) {
+				w.chunk.Content = ""
+				sendFileChunkError(wsCtx, ActionDownloadDir, w.chunk, err)
+			}
+		}
+	})
+	return nil
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.hash.Write(p)
+	w.buf = append(w.buf, p...)
+	for len(w.buf) >= archiveChunkSize {
+		if err := w.send(w.buf[:archiveChunkSize]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[archiveChunkSize:]
+	}
+	return len(p), nil
+}
+
+func (w *chunkWriter) send(data []byte) error {
+	w.chunk.Status = FileStatusInProgress
+	w.chunk.Content = base64.StdEncoding.EncodeToString(data)
+	w.chunk.Progress = &w.progress
+	if err := sendFileChunk(w.wsCtx, w.action, w.chunk, StatusInProgress); err != nil {
+		return err
+	}
+	w.chunk.Index++
+	return nil
+}
+
+// 남은 데이터와 체크섬을 포함한 마지막 청크 전송
+func (w *chunkWriter) finish() error {
+	if len(w.buf) > 0 {
+		if err := w.send(w.buf); err != nil {
+			return err
+		}
+		w.buf = nil
+	}
+
+	w.chunk.Status = FileStatusSuccess
+	w.chunk.Content = ""
+	w.chunk.Checksum = fmt.Sprintf("%x", w.hash.Sum(nil))
+	w.chunk.Progress = &w.progress
+	return sendFileChunk(w.wsCtx, w.action, w.chunk, StatusSuccess)
+}
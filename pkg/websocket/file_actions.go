@@ -10,6 +10,8 @@ import (
 	"log"
 	"path"
 
+	"sshbck/pkg/sshclient"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -21,8 +23,18 @@ type FileChunk struct {
 	Index    int    `json:"index"`
 	Content  string `json:"content"`
 	Checksum string `json:"checksum"`
+	Version  string `json:"version,omitempty"` // 마지막 청크에만 포함 (저장 시 baseVersion으로 사용)
+	Event    string `json:"event,omitempty"`   // tailfile 중 파일 변경 (truncated, rotated)
+
+	Progress *ArchiveProgress `json:"progress,omitempty"` // downloaddir 진행 상황
 }
 
+// 구간 읽기 최대 크기
+const maxRangeLength = 1 << 20
+
+// 저장 충돌 오류 코드
+const ErrCodeEditConflict = "EDIT_CONFLICT"
+
 const (
 	FileStatusInProgress Status = "in-progress"
 	FileStatusSuccess    Status = "success"
@@ -30,8 +42,8 @@ const (
 )
 
 // 파일 목록 조회
-func handleGetFileList(wsCtx *WSHandlerContext, requestData map[string]interface{}) error {
-	root := requestData["root"].(string)
+func handleGetFileList(wsCtx *WSHandlerContext, req getFileListRequest) error {
+	root := req.Root
 	if root == "HOME_DIR" {
 		root, _ = wsCtx.ssh.HomeDir()
 	}
@@ -41,74 +53,93 @@ func handleGetFileList(wsCtx *WSHandlerContext, requestData map[string]interface
 		return errors.New("file list error: " + err.Error())
 	}
 
-	data := map[string]interface{}{
-		"parent":   path.Clean(root),
-		"fileTree": files,
-	}
-
-	msg, err := toJSON(data)
+	msg, err := toJSON(fileListResponse{
+		Parent:   path.Clean(root),
+		FileTree: files,
+	})
 	if err != nil {
 		return errors.New("json marshal error: " + err.Error())
 	}
 
-	wsCtx.safeWS.WriteJSON(createMessage(string(ActionGetFileList), msg, StatusSuccess, ""))
+	wsCtx.safeWS.WriteJSON(wsCtx.message(ActionGetFileList, msg, StatusSuccess, ""))
 	return nil
 }
 
 // 파일 콘텐츠 조회
-func handleGetFileContents(wsCtx *WSHandlerContext, requestData map[string]interface{}) error {
-	path := requestData["path"].(string)
-	go streamFileContent(wsCtx, path)
+func handleGetFileContents(wsCtx *WSHandlerContext, req getFileContentsRequest) error {
+	wsCtx.goSafe("stream file", func() {
+		streamFileContent(wsCtx, req.Path)
+	})
 	return nil
 }
 
-// 파일 콘텐츠 저장
-func handleSaveFileChunk(wsCtx *WSHandlerContext, requestData map[string]interface{}) error {
-	path := requestData["path"].(string)
-	content, _ := base64.StdEncoding.DecodeString(requestData["content"].(string))
-	isFirstChunk := requestData["isFirstChunk"].(bool)
-	isLastChunk := requestData["isLastChunk"].(bool)
-	checksum, _ := requestData["checksum"].(string)
-
-	err := wsCtx.ssh.SaveFileChunkWithChecksum(path, content, isFirstChunk, isLastChunk, checksum)
+// 파일 일부 구간 읽기 (큰 파일의 가상 스크롤, 헥스 뷰어용)
+func handleReadRange(wsCtx *WSHandlerContext, req readRangeRequest) error {
+	var result sshclient.FileRange
+	var err error
+	switch {
+	case req.LastLines > 0:
+		result, err = wsCtx.ssh.ReadLastLines(req.Path, req.LastLines, maxRangeLength)
+	case req.LastBytes > 0:
+		result, err = wsCtx.ssh.ReadLastBytes(req.Path, req.LastBytes)
+	default:
+		result, err = wsCtx.ssh.ReadRange(req.Path, req.Offset, req.Length)
+	}
 	if err != nil {
-		return errors.New("file write error: " + err.Error())
+		return errors.New("file read error: " + err.Error())
 	}
 
-	data := map[string]interface{}{
-		"path": path,
+	msg, err := toJSON(rangeResponse{
+		Path:    req.Path,
+		Offset:  result.Offset,
+		Size:    result.Size,
+		Content: base64.StdEncoding.EncodeToString(result.Content),
+		EOF:     result.Offset+int64(len(result.Content)) >= result.Size,
+	})
+	if err != nil {
+		return errors.New("json marshal error: " + err.Error())
+	}
+
+	wsCtx.safeWS.WriteJSON(wsCtx.message(ActionReadRange, msg, StatusSuccess, ""))
+	return nil
+}
+
+// 파일 콘텐츠 저장
+func handleSaveFileChunk(wsCtx *WSHandlerContext, req saveFileChunkRequest) error {
+	version, err := wsCtx.ssh.SaveFileChunkWithChecksum(req.Path, req.content, req.IsFirstChunk, req.IsLastChunk, req.Checksum, req.BaseVersion)
+	if sendConflict(wsCtx, ActionSaveFileChunk, err) {
+		return nil
+	} else if err != nil {
+		return errors.New("file write error: " + err.Error())
 	}
 
-	msg, err := toJSON(data)
+	msg, err := toJSON(savedFileResponse{Path: req.Path, Version: version})
 	if err != nil {
 		return errors.New("json marshal error: " + err.Error())
 	}
 
-	wsCtx.safeWS.WriteJSON(createMessage(string(ActionSaveFileChunk), msg, StatusSuccess, ""))
+	wsCtx.safeWS.WriteJSON(wsCtx.message(ActionSaveFileChunk, msg, StatusSuccess, ""))
 	return nil
 }
 
 // 파일 추가
-func handleAddFile(wsCtx *WSHandlerContext, requestData map[string]interface{}) error {
-	parentPath := requestData["parentPath"].(string)
-	filename := requestData["filename"].(string)
-	if err := wsCtx.ssh.AddFile(parentPath + "/" + filename); err != nil {
+func handleAddFile(wsCtx *WSHandlerContext, req addFileRequest) error {
+	if err := wsCtx.ssh.AddFile(req.ParentPath + "/" + req.Filename); err != nil {
 		return errors.New("file add error: " + err.Error())
 	}
 
-	wsCtx.safeWS.WriteJSON(createMessage(string(ActionAddFile), nil, StatusSuccess, ""))
+	wsCtx.safeWS.WriteJSON(wsCtx.message(ActionAddFile, nil, StatusSuccess, ""))
 	return nil
 }
 
 // 파일 삭제
-func handleRemoveFile(wsCtx *WSHandlerContext, requestData map[string]interface{}) error {
-	log.Println("handleRemoveFile", requestData)
-	path := requestData["fullPath"].(string)
-	if err := wsCtx.ssh.RemoveFile(path); err != nil {
+func handleRemoveFile(wsCtx *WSHandlerContext, req removeFileRequest) error {
+	log.Println("handleRemoveFile", req.FullPath)
+	if err := wsCtx.ssh.RemoveFile(req.FullPath); err != nil {
 		return errors.New("file remove error: " + err.Error())
 	}
 
-	wsCtx.safeWS.WriteJSON(createMessage(string(ActionRemoveFile), nil, StatusSuccess, ""))
+	wsCtx.safeWS.WriteJSON(wsCtx.message(ActionRemoveFile, nil, StatusSuccess, ""))
 	return nil
 }
 
@@ -118,10 +149,18 @@ func streamFileContent(wsCtx *WSHandlerContext, path string) {
 	file, err := wsCtx.ssh.SFTPClient.Open(path)
 	if err != nil {
 		log.Println("File read error:", err)
+		sendFileChunkError(wsCtx, ActionGetFileContents, FileChunk{FileHash: fileHash, Path: path}, err)
 		return
 	}
 	defer file.Close()
 
+	info, err := file.Stat()
+	if err != nil {
+		log.Println("File stat error:", err)
+		sendFileChunkError(wsCtx, ActionGetFileContents, FileChunk{FileHash: fileHash, Path: path}, err)
+		return
+	}
+
 	writable := wsCtx.ssh.CheckWritePermission(path)
 
 	// SHA-256 체크섬 계산기 초기화
@@ -152,7 +191,7 @@ func streamFileContent(wsCtx *WSHandlerContext, path string) {
 				return
 			}
 
-			if err := wsCtx.safeWS.WriteMessage(websocket.TextMessage, createMessage(string(ActionGetFileContents), msg, StatusSuccess, "")); err != nil {
+			if err := wsCtx.safeWS.WriteMessage(websocket.TextMessage, wsCtx.message(ActionGetFileContents, msg, StatusSuccess, "")); err != nil {
 				log.Println("WebSocket write error:", err)
 				return
 			}
@@ -161,12 +200,14 @@ func streamFileContent(wsCtx *WSHandlerContext, path string) {
 			break
 		} else if err != nil {
 			log.Println("File read error:", err)
+			sendFileChunkError(wsCtx, ActionGetFileContents, FileChunk{FileHash: fileHash, Path: path, Index: idx}, err)
 			return
 		}
 
 		idx++
 	}
 	finalHash := hash.Sum(nil)
+	checksum := fmt.Sprintf("%x", finalHash)
 
 	finalChunk := FileChunk{
 		FileHash: fileHash,
@@ -175,16 +216,53 @@ func streamFileContent(wsCtx *WSHandlerContext, path string) {
 		Writable: writable,
 		Index:    idx,
 		Content:  "",
-		Checksum: fmt.Sprintf("%x", finalHash),
+		Checksum: checksum,
+		Version:  sshclient.FileVersion(info.ModTime(), info.Size(), checksum),
 	}
 	msg, err := json.Marshal(finalChunk)
 	if err != nil {
 		log.Println("JSON marshal error:", err)
 		return
 	}
-	err = wsCtx.safeWS.WriteMessage(websocket.TextMessage, createMessage(string(ActionGetFileContents), msg, StatusSuccess, ""))
+	err = wsCtx.safeWS.WriteMessage(websocket.TextMessage, wsCtx.message(ActionGetFileContents, msg, StatusSuccess, ""))
 
 	if err != nil {
 		log.Println("websocket write error:", err)
 	}
 }
+
+// 저장 충돌이면 충돌 오류와 현재 원격 파일 내용을 전송하고 true 반환
+func sendConflict(wsCtx *WSHandlerContext, action Action, err error) bool {
+	var conflict *sshclient.ConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+
+	msg, marshalErr := toJSON(conflictResponse{Path: conflict.Path, Version: conflict.Version})
+	if marshalErr != nil {
+		log.Println("JSON marshal error:", marshalErr)
+		return true
+	}
+	wsCtx.safeWS.WriteJSON(wsCtx.errorMessage(action, msg, ErrCodeEditConflict, err.Error()))
+
+	// 병합 화면을 위해 현재 내용 전송 (파일이 삭제되었으면 생략)
+	if conflict.Version != "" {
+		wsCtx.goSafe("stream conflict", func() {
+			streamFileContent(wsCtx, conflict.Path)
+		})
+	}
+	return true
+}
+
+// 파일 청크 전송 실패 알림
+func sendFileChunkError(wsCtx *WSHandlerContext, action Action, chunk FileChunk, err error) {
+	chunk.Status = FileStatusFailed
+	msg, marshalErr := json.Marshal(chunk)
+	if marshalErr != nil {
+		log.Println("JSON marshal error:", marshalErr)
+		return
+	}
+	if err := wsCtx.safeWS.WriteMessage(websocket.TextMessage, wsCtx.message(action, msg, StatusFailed, err.Error())); err != nil {
+		log.Println("websocket write error:", err)
+	}
+}
@@ -0,0 +1,164 @@
+package websocket
+
+import (
+	"errors"
+	"log"
+	"path"
+	"strconv"
+
+	"sshbck/pkg/sshclient"
+)
+
+// 이름 변경
+func handleRename(wsCtx *WSHandlerContext, req renameRequest) error {
+	newPath := path.Join(path.Dir(req.Path), req.NewName)
+	if err := wsCtx.ssh.Rename(req.Path, newPath, false); err != nil {
+		return errors.New("rename error: " + err.Error())
+	}
+	return sendPath(wsCtx, ActionRename, newPath)
+}
+
+// 디렉토리 생성 (mkdir -p)
+func handleMakeDir(wsCtx *WSHandlerContext, req makeDirRequest) error {
+	if err := wsCtx.ssh.MakeDir(req.Path); err != nil {
+		return errors.New("mkdir error: " + err.Error())
+	}
+	return sendPath(wsCtx, ActionMakeDir, path.Clean(req.Path))
+}
+
+// 여러 항목을 대상 디렉토리로 이동
+func handleMove(wsCtx *WSHandlerContext, req transferRequest) error {
+	runFileOp(wsCtx, ActionMove, req.Paths, func(src string, notify sshclient.ItemFunc) error {
+		dst := path.Join(req.Destination, path.Base(src))
+		err := wsCtx.ssh.Rename(src, dst, req.Overwrite)
+		notify(src, dst, err)
+		return err
+	})
+	return nil
+}
+
+// 여러 항목을 대상 디렉토리로 복사 (디렉토리는 하위 항목까지)
+func handleCopy(wsCtx *WSHandlerContext, req transferRequest) error {
+	runFileOp(wsCtx, ActionCopy, req.Paths, func(src string, notify sshclient.ItemFunc) error {
+		return wsCtx.ssh.Copy(src, path.Join(req.Destination, path.Base(src)), req.Overwrite, notify)
+	})
+	return nil
+}
+
+// 여러 항목을 하위 항목까지 삭제
+func handleRemoveAll(wsCtx *WSHandlerContext, req removeAllRequest) error {
+	runFileOp(wsCtx, ActionRemoveAll, req.Paths, func(src string, notify sshclient.ItemFunc) error {
+		return wsCtx.ssh.RemoveAll(src, notify)
+	})
+	return nil
+}
+
+// 권한 변경
+func handleChmod(wsCtx *WSHandlerContext, req chmodRequest) error {
+	runPermOp(wsCtx, ActionChmod, req.Path, func(src string, notify sshclient.ItemFunc) error {
+		return wsCtx.ssh.Chmod(src, req.mode, req.Recursive, notify)
+	})
+	return nil
+}
+
+// 소유자 및 그룹 변경 (그룹은 사용자가 속한 그룹만 허용)
+func handleChown(wsCtx *WSHandlerContext, req chownRequest) error {
+	if req.Group != "" {
+		groups, err := wsCtx.ssh.GetGroups()
+		if err != nil {
+			return errors.New("group retrieval error: " + err.Error())
+		}
+		if !contains(groups, req.Group) {
+			return errors.New("not a member of group: " + req.Group)
+		}
+	}
+
+	runPermOp(wsCtx, ActionChown, req.Path, func(src string, notify sshclient.ItemFunc) error {
+		return wsCtx.ssh.Chown(src, req.Owner, req.Group, req.Recursive, notify)
+	})
+	return nil
+}
+
+// 권한 변경 작업 실행 후 변경된 파일 정보를 결과에 포함
+func runPermOp(wsCtx *WSHandlerContext, action Action, target string, op func(src string, notify sshclient.ItemFunc) error) {
+	wsCtx.goSafe(string(action), func() {
+		result := applyFileOp(wsCtx, action, []string{target}, op)
+		if info, err := wsCtx.ssh.Stat(target); err == nil {
+			result.File = &info
+		}
+		sendFileOpResult(wsCtx, action, result)
+	})
+}
+
+// 항목별 작업 실행 후 진행 상황과 최종 결과 전송 (일부 실패해도 나머지는 계속 진행)
+func runFileOp(wsCtx *WSHandlerContext, action Action, paths []string, op func(src string, notify sshclient.ItemFunc) error) {
+	wsCtx.goSafe(string(action), func() {
+		result := applyFileOp(wsCtx, action, paths, op)
+		sendFileOpResult(wsCtx, action, result)
+	})
+}
+
+// 항목별 작업 실행 (항목마다 진행 상황 전송)
+func applyFileOp(wsCtx *WSHandlerContext, action Action, paths []string, op func(src string, notify sshclient.ItemFunc) error) fileOpResult {
+	var result fileOpResult
+	notify := func(src, dst string, err error) {
+		item := fileOpProgress{Path: src, Target: dst, Status: StatusSuccess}
+		if errors.Is(err, sshclient.ErrSkipped) {
+			item.Status = StatusSkipped
+			item.Error = err.Error()
+			result.Skipped++
+		} else if err != nil {
+			item.Status = StatusFailed
+			item.Error = err.Error()
+			result.Failed = append(result.Failed, item)
+		} else {
+			result.Succeeded++
+		}
+		sendFileOp(wsCtx, action, item, StatusInProgress, "")
+	}
+
+	for _, src := range paths {
+		failed := len(result.Failed)
+		if err := op(src, notify); err != nil && len(result.Failed) == failed {
+			// 항목 처리 전에 실패한 경우 (대상이 이미 있는 경우 등)
+			notify(src, "", err)
+		}
+	}
+	return result
+}
+
+func sendFileOpResult(wsCtx *WSHandlerContext, action Action, result fileOpResult) {
+	result.Done = true
+	if n := len(result.Failed); n > 0 {
+		sendFileOp(wsCtx, action, result, StatusFailed, strconv.Itoa(n)+" items failed")
+	} else {
+		sendFileOp(wsCtx, action, result, StatusSuccess, "")
+	}
+}
+
+func sendFileOp(wsCtx *WSHandlerContext, action Action, data interface{}, status Status, errMsg string) {
+	msg, err := toJSON(data)
+	if err != nil {
+		log.Println("JSON marshal error:", err)
+		return
+	}
+	wsCtx.safeWS.WriteJSON(wsCtx.message(action, msg, status, errMsg))
+}
+
+func sendPath(wsCtx *WSHandlerContext, action Action, p string) error {
+	msg, err := toJSON(pathResponse{Path: p})
+	if err != nil {
+		return errors.New("json marshal error: " + err.Error())
+	}
+	wsCtx.safeWS.WriteJSON(wsCtx.message(action, msg, StatusSuccess, ""))
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"errors"
+	"net"
+
+	"sshbck/pkg/sshclient"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 호스트 키 오류 코드
+const (
+	ErrCodeHostKeyMismatch = "HOST_KEY_MISMATCH"
+	ErrCodeHostKeyUnknown  = "HOST_KEY_UNKNOWN"
+	ErrCodeHostKeyRejected = "HOST_KEY_REJECTED"
+)
+
+// 요청된 정책과 브릿지 설정으로 호스트 키 정책 결정 (브릿지가 strict이면 완화 불가)
+func hostKeyPolicy(requested string) sshclient.HostKeyPolicy {
+	if options.HostKeyPolicy == sshclient.HostKeyStrict {
+		return sshclient.HostKeyStrict
+	}
+	if sshclient.HostKeyPolicy(requested) == sshclient.HostKeyStrict {
+		return sshclient.HostKeyStrict
+	}
+	return sshclient.HostKeyTOFU
+}
+
+// known_hosts 기반 호스트 키 검증 콜백 생성
+func hostKeyCallback(wsCtx *WSHandlerContext, policy sshclient.HostKeyPolicy) ssh.HostKeyCallback {
+	if options.KnownHosts == nil {
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			return errors.New("host key verification is not configured")
+		}
+	}
+
+	callback := options.KnownHosts.Callback(policy, confirmHostKey(wsCtx))
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+
+		var hostKeyErr *sshclient.HostKeyError
+		if errors.As(err, &hostKeyErr) {
+			msg, _ := toJSON(hostKeyResponse{
+				Host:        hostKeyErr.Hostname,
+				KeyType:     hostKeyErr.KeyType,
+				Fingerprint: hostKeyErr.Fingerprint,
+			})
+			wsCtx.safeWS.WriteJSON(wsCtx.errorMessage(ActionHostKey, msg, hostKeyErrorCode(err), err.Error()))
+		}
+		return err
+	}
+}
+
+// 처음 보는 호스트 키를 클라이언트에 확인 요청
+func confirmHostKey(wsCtx *WSHandlerContext) sshclient.HostKeyConfirm {
+	return func(hostname string, key ssh.PublicKey) (bool, error) {
+		msg, err := toJSON(hostKeyResponse{
+			Host:        hostname,
+			KeyType:     key.Type(),
+			Fingerprint: ssh.FingerprintSHA256(key),
+		})
+		if err != nil {
+			return false, errors.New("json marshal error: " + err.Error())
+		}
+
+		reply, err := wsCtx.awaitReply(ActionHostKey, msg)
+		if err != nil {
+			return false, err
+		}
+		return reply.(hostKeyReply).Accept, nil
+	}
+}
+
+// 호스트 키 확인 응답
+func handleHostKey(wsCtx *WSHandlerContext, reply hostKeyReply) error {
+	return deliverReply(wsCtx, ActionHostKey, reply)
+}
+
+func hostKeyErrorCode(err error) string {
+	switch {
+	case errors.Is(err, sshclient.ErrHostKeyMismatch):
+		return ErrCodeHostKeyMismatch
+	case errors.Is(err, sshclient.ErrHostKeyRejected):
+		return ErrCodeHostKeyRejected
+	default:
+		return ErrCodeHostKeyUnknown
+	}
+}
@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 클라이언트 응답 대기 시간
+const replyTimeout = 2 * time.Minute
+
+// 클라이언트 응답을 기다리는 요청 목록 (action 당 하나)
+type pendingReplies struct {
+	mu      sync.Mutex
+	waiters map[Action]chan interface{}
+}
+
+func newPendingReplies() *pendingReplies {
+	return &pendingReplies{waiters: make(map[Action]chan interface{})}
+}
+
+// 클라이언트에 질의를 보내고 응답 대기
+func (wsCtx *WSHandlerContext) awaitReply(action Action, data []byte) (interface{}, error) {
+	replyCh := make(chan interface{}, 1)
+
+	wsCtx.replies.mu.Lock()
+	if _, exists := wsCtx.replies.waiters[action]; exists {
+		wsCtx.replies.mu.Unlock()
+		return nil, errors.New("another " + string(action) + " request is pending")
+	}
+	wsCtx.replies.waiters[action] = replyCh
+	wsCtx.replies.mu.Unlock()
+
+	defer func() {
+		wsCtx.replies.mu.Lock()
+		delete(wsCtx.replies.waiters, action)
+		wsCtx.replies.mu.Unlock()
+	}()
+
+	if err := wsCtx.safeWS.WriteJSON(wsCtx.message(action, data, StatusInProgress, "")); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(replyTimeout)
+	defer timer.Stop()
+
+	select {
+	case reply := <-replyCh:
+		return reply, nil
+	case <-timer.C:
+		wsCtx.safeWS.WriteJSON(wsCtx.message(action, nil, StatusFailed, "timed out waiting for reply"))
+		return nil, errors.New(string(action) + " timed out")
+	case <-wsCtx.ctx.Done():
+		return nil, wsCtx.ctx.Err()
+	}
+}
+
+// 클라이언트 응답 전달
+func deliverReply(wsCtx *WSHandlerContext, action Action, reply interface{}) error {
+	wsCtx.replies.mu.Lock()
+	replyCh, ok := wsCtx.replies.waiters[action]
+	wsCtx.replies.mu.Unlock()
+	if !ok {
+		return errors.New("no pending " + string(action) + " request")
+	}
+
+	select {
+	case replyCh <- reply:
+	default:
+		return errors.New("duplicate " + string(action) + " reply")
+	}
+	return nil
+}
+
+// keyboard-interactive 응답
+func handleKeyboardInteractive(wsCtx *WSHandlerContext, reply keyboardInteractiveReply) error {
+	return deliverReply(wsCtx, ActionKeyboardInteractive, reply)
+}
+
+// keyboard-interactive 질의를 클라이언트로 전달하는 challenge 함수 생성
+func newKeyboardInteractiveChallenge(wsCtx *WSHandlerContext) ssh.KeyboardInteractiveChallenge {
+	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		if len(questions) == 0 {
+			return []string{}, nil
+		}
+
+		msg, err := toJSON(keyboardInteractiveChallenge{
+			Name:        name,
+			Instruction: instruction,
+			Prompts:     questions,
+			Echos:       echos,
+		})
+		if err != nil {
+			return nil, errors.New("json marshal error: " + err.Error())
+		}
+
+		reply, err := wsCtx.awaitReply(ActionKeyboardInteractive, msg)
+		if err != nil {
+			return nil, err
+		}
+		answer := reply.(keyboardInteractiveReply)
+		if answer.Cancel {
+			return nil, errors.New("keyboard-interactive cancelled")
+		}
+		if len(answer.Answers) != len(questions) {
+			return nil, errors.New("keyboard-interactive answer count mismatch")
+		}
+		return answer.Answers, nil
+	}
+}
@@ -1,9 +1,15 @@
 package websocket
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+)
 
 type (
-	messageHandler func(wsCtx *WSHandlerContext, message map[string]interface{}) error
+	messageHandler func(wsCtx *WSHandlerContext, message WSMessage) error
 
 	messageRouter struct {
 		handlers map[Action]messageHandler
@@ -18,11 +24,57 @@ func (r *messageRouter) RegisterHandler(action Action, handler messageHandler) {
 	r.handlers[action] = handler
 }
 
-func (r *messageRouter) Route(wsCtx *WSHandlerContext, message WSMessage) error {
+// 메시지를 핸들러로 전달 (핸들러의 panic은 오류로 변환)
+func (r *messageRouter) Route(wsCtx *WSHandlerContext, message WSMessage) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("handler panic (%s): %v\n%s", message.Action, rec, debug.Stack())
+			err = fmt.Errorf("internal error while handling %s", message.Action)
+		}
+	}()
+
 	action := message.Action
 	handler, ok := r.handlers[action]
 	if !ok {
 		return errors.New("unsupported action: " + string(action))
 	}
-	return handler(wsCtx, message.Data)
+	return handler(wsCtx.forRequest(message.ID), message)
+}
+
+// 요청 데이터를 T로 디코딩하고 검증한 뒤 호출하는 핸들러 생성
+func typed[T any](handler func(wsCtx *WSHandlerContext, req T) error) messageHandler {
+	return func(wsCtx *WSHandlerContext, message WSMessage) error {
+		var req T
+		if err := decodeData(message.Data, &req); err != nil {
+			return err
+		}
+		return handler(wsCtx, req)
+	}
+}
+
+// 요청 데이터 디코딩 및 검증
+func decodeData(data json.RawMessage, v interface{}) error {
+	if len(data) > 0 && string(data) != "null" {
+		if err := json.Unmarshal(data, v); err != nil {
+			return errors.New("invalid request: " + err.Error())
+		}
+	}
+	if req, ok := v.(validator); ok {
+		if err := req.validate(); err != nil {
+			return errors.New("invalid request: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// panic이 발생해도 다른 세션에 영향을 주지 않도록 고루틴 실행
+func (wsCtx *WSHandlerContext) goSafe(name string, fn func()) {
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				log.Printf("goroutine panic (%s): %v\n%s", name, rec, debug.Stack())
+			}
+		}()
+		fn()
+	}()
 }
@@ -0,0 +1,685 @@
+package websocket
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"sshbck/pkg/sshclient"
+)
+
+// 요청 검증 인터페이스 (요청 구조체가 구현하면 디코딩 후 호출됨)
+type validator interface {
+	validate() error
+}
+
+// 요청 스키마
+type (
+	// 접속할 호스트 (대상 또는 점프 호스트)
+	hostRequest struct {
+		Host          string   `json:"host"`
+		Port          string   `json:"port"`
+		Username      string   `json:"username"`
+		Password      string   `json:"password"`
+		PrivateKey    string   `json:"privateKey"`
+		Passphrase    string   `json:"passphrase"`
+		Certificate   string   `json:"certificate"`
+		KeyRef        string   `json:"keyRef"`
+		UseAgent      bool     `json:"useAgent"`
+		AuthOrder     []string `json:"authOrder"`
+		HostKeyPolicy string   `json:"hostKeyPolicy"`
+	}
+
+	connectRequest struct {
+		hostRequest
+		Channel   string        `json:"channel"` // 기본 PTY 채널 ID (선택)
+		Cols      int           `json:"cols"`
+		Rows      int           `json:"rows"`
+		JumpHosts []hostRequest `json:"jumpHosts"`
+	}
+
+	resizeRequest struct {
+		Channel string `json:"channel"`
+		Cols    int    `json:"cols"`
+		Rows    int    `json:"rows"`
+	}
+
+	terminalRequest struct {
+		Channel string `json:"channel"`
+		Data    string `json:"data"`
+	}
+
+	openTerminalRequest struct {
+		Channel string `json:"channel"`
+		Cols    int    `json:"cols"`
+		Rows    int    `json:"rows"`
+	}
+
+	closeTerminalRequest struct {
+		Channel string `json:"channel"`
+	}
+
+	getFileListRequest struct {
+		Root string `json:"root"`
+	}
+
+	getFileContentsRequest struct {
+		Path string `json:"path"`
+	}
+
+	// offset/length 구간 또는 마지막 lastBytes 바이트, 마지막 lastLines 줄 중 하나를 읽음
+	readRangeRequest struct {
+		Path      string `json:"path"`
+		Offset    int64  `json:"offset"`
+		Length    int64  `json:"length"`
+		LastBytes int64  `json:"lastBytes"`
+		LastLines int    `json:"lastLines"`
+	}
+
+	tailFileRequest struct {
+		Path      string `json:"path"`
+		LastLines int    `json:"lastLines"` // 시작 시 먼저 보낼 마지막 줄 수 (0이면 새로 추가된 내용만)
+		Interval  int    `json:"interval"`  // 확인 간격 (밀리초, 기본 1000)
+	}
+
+	downloadDirRequest struct {
+		Path    string   `json:"path"`
+		Format  string   `json:"format"` // tar.gz (기본값) 또는 zip
+		Include []string `json:"include"`
+		Exclude []string `json:"exclude"`
+		MaxSize int64    `json:"maxSize"` // 원본 크기 합계 제한 (0이면 브릿지 기본값)
+	}
+
+	searchFilesRequest struct {
+		Path           string   `json:"path"`
+		Name           []string `json:"name"`       // 파일 이름 또는 상대 경로 glob
+		Content        string   `json:"content"`    // 내용 정규식 (RE2)
+		IgnoreCase     bool     `json:"ignoreCase"` // 내용 검색 시 대소문자 무시
+		MinSize        int64    `json:"minSize"`
+		MaxSize        int64    `json:"maxSize"`
+		ModifiedAfter  string   `json:"modifiedAfter"`  // RFC3339
+		ModifiedBefore string   `json:"modifiedBefore"` // RFC3339
+		MaxDepth       int      `json:"maxDepth"`       // 0이면 제한 없음
+		MaxResults     int      `json:"maxResults"`     // 0이면 브릿지 기본값
+
+		content        *regexp.Regexp
+		modifiedAfter  time.Time
+		modifiedBefore time.Time
+	}
+
+	cancelRequest struct {
+		RequestID string `json:"requestId"` // 취소할 작업을 시작한 요청의 ID
+	}
+
+	saveFileChunkRequest struct {
+		Path         string `json:"path"`
+		Content      string `json:"content"` // base64
+		IsFirstChunk bool   `json:"isFirstChunk"`
+		IsLastChunk  bool   `json:"isLastChunk"`
+		Checksum     string `json:"checksum"`
+		BaseVersion  string `json:"baseVersion"` // 파일을 읽을 때 받은 버전 (선택)
+
+		content []byte
+	}
+
+	addFileRequest struct {
+		ParentPath string `json:"parentPath"`
+		Filename   string `json:"filename"`
+	}
+
+	removeFileRequest struct {
+		FullPath string `json:"fullPath"`
+	}
+
+	renameRequest struct {
+		Path    string `json:"path"`
+		NewName string `json:"newName"`
+	}
+
+	// 여러 항목을 대상 디렉토리로 이동 또는 복사
+	transferRequest struct {
+		Paths       []string `json:"paths"`
+		Destination string   `json:"destination"`
+		Overwrite   bool     `json:"overwrite"`
+	}
+
+	makeDirRequest struct {
+		Path string `json:"path"`
+	}
+
+	removeAllRequest struct {
+		Paths []string `json:"paths"`
+	}
+
+	chmodRequest struct {
+		Path      string `json:"path"`
+		Mode      string `json:"mode"` // 8진수 (예: "0755")
+		Recursive bool   `json:"recursive"`
+
+		mode os.FileMode
+	}
+
+	chownRequest struct {
+		Path      string `json:"path"`
+		Owner     string `json:"owner"` // 빈 값이면 변경하지 않음
+		Group     string `json:"group"` // 빈 값이면 변경하지 않음
+		Recursive bool   `json:"recursive"`
+	}
+
+	uploadStartRequest struct {
+		Path      string `json:"path"`
+		Size      int64  `json:"size"`
+		Algorithm string `json:"algorithm"` // sha256 (기본값), blake2b-256, blake2b-512
+	}
+
+	uploadChunkRequest struct {
+		UploadID string `json:"uploadId"`
+		Offset   int64  `json:"offset"`
+		Content  string `json:"content"` // base64
+
+		content []byte
+	}
+
+	// 업로드 상태 조회 및 취소
+	uploadRequest struct {
+		UploadID string `json:"uploadId"`
+	}
+
+	uploadCommitRequest struct {
+		UploadID    string `json:"uploadId"`
+		Checksum    string `json:"checksum"`    // 업로드 시작 시 지정한 알고리즘의 hex 체크섬
+		BaseVersion string `json:"baseVersion"` // 파일을 읽을 때 받은 버전 (선택)
+		Verify      bool   `json:"verify"`      // 저장 전에 임시 파일을 다시 읽어 확인
+	}
+
+	// 업로드한 압축 파일을 대상 디렉토리에 해제 (uploadcommit 대신 사용)
+	uploadExtractRequest struct {
+		UploadID    string `json:"uploadId"`
+		Checksum    string `json:"checksum"`
+		Destination string `json:"destination"`
+		Format      string `json:"format"` // tar.gz 또는 zip (빈 값이면 업로드 파일 이름으로 판단)
+		Policy      string `json:"policy"` // 기존 파일 처리: fail (기본값), skip, overwrite
+	}
+
+	resumeRequest struct {
+		Token string `json:"token"`
+	}
+
+	emptyRequest struct{}
+
+	keyboardInteractiveReply struct {
+		Answers []string `json:"answers"`
+		Cancel  bool     `json:"cancel"`
+	}
+
+	hostKeyReply struct {
+		Accept bool `json:"accept"`
+	}
+)
+
+// 응답 스키마
+type (
+	connectionStateResponse struct {
+		State      string `json:"state"`
+		Hop        *int   `json:"hop,omitempty"`
+		Address    string `json:"address,omitempty"`
+		ExitStatus *int   `json:"exitStatus,omitempty"`
+
+		ResumeToken string   `json:"resumeToken,omitempty"` // 재연결 시 사용할 토큰
+		Channels    []string `json:"channels,omitempty"`    // 재연결 시 열려 있는 PTY 채널
+	}
+
+	terminalResponse struct {
+		Channel    string `json:"channel"`
+		ExitStatus *int   `json:"exitStatus,omitempty"`
+	}
+
+	fileListResponse struct {
+		Parent   string               `json:"parent"`
+		FileTree []sshclient.FileInfo `json:"fileTree"`
+	}
+
+	// 파일 검색 종료 (검색 결과는 in-progress 메시지로 하나씩 전송)
+	searchResultResponse struct {
+		sshclient.SearchStats
+		Done     bool `json:"done"`
+		Canceled bool `json:"canceled"`
+	}
+
+	rangeResponse struct {
+		Path    string `json:"path"`
+		Offset  int64  `json:"offset"` // 읽은 구간의 시작 위치
+		Size    int64  `json:"size"`   // 전체 파일 크기
+		Content string `json:"content"`
+		EOF     bool   `json:"eof"`
+	}
+
+	savedFileResponse struct {
+		Path    string `json:"path"`
+		Version string `json:"version,omitempty"` // 저장된 파일의 버전
+	}
+
+	// 저장 충돌 (이어서 현재 원격 파일 내용을 getfilecontents 청크로 전송)
+	conflictResponse struct {
+		Path    string `json:"path"`
+		Version string `json:"version"` // 현재 원격 파일 버전 (삭제되었으면 빈 문자열)
+	}
+
+	uploadResponse struct {
+		UploadID  string            `json:"uploadId"`
+		Path      string            `json:"path"`
+		Size      int64             `json:"size"`
+		Algorithm string            `json:"algorithm"`
+		Ranges    []sshclient.Range `json:"ranges"` // 수신한 구간
+	}
+
+	pathResponse struct {
+		Path string `json:"path"`
+	}
+
+	// 여러 항목 작업의 항목별 진행 상황
+	fileOpProgress struct {
+		Path   string `json:"path"`
+		Target string `json:"target,omitempty"`
+		Status Status `json:"status"`
+		Error  string `json:"error,omitempty"`
+	}
+
+	// 여러 항목 작업의 최종 결과
+	fileOpResult struct {
+		Done      bool             `json:"done"`
+		Succeeded int              `json:"succeeded"`
+		Skipped   int              `json:"skipped,omitempty"`
+		Failed    []fileOpProgress `json:"failed,omitempty"`
+
+		File *sshclient.FileInfo `json:"file,omitempty"` // 변경 후 파일 정보 (권한 변경 작업)
+	}
+
+	groupsResponse struct {
+		Groups []string `json:"groups"`
+	}
+
+	keyboardInteractiveChallenge struct {
+		Name        string   `json:"name"`
+		Instruction string   `json:"instruction"`
+		Prompts     []string `json:"prompts"`
+		Echos       []bool   `json:"echos"`
+	}
+
+	hostKeyResponse struct {
+		Host        string `json:"host"`
+		KeyType     string `json:"keyType"`
+		Fingerprint string `json:"fingerprint"`
+	}
+)
+
+func (r *hostRequest) validate() error {
+	if r.Host == "" {
+		return errors.New("host is required")
+	}
+	if port, err := strconv.Atoi(r.Port); err != nil || port < 1 || port > 65535 {
+		return errors.New("invalid port: " + r.Port)
+	}
+	if r.Username == "" {
+		return errors.New("username is required")
+	}
+	return nil
+}
+
+func (r *connectRequest) validate() error {
+	if err := r.hostRequest.validate(); err != nil {
+		return err
+	}
+	for i := range r.JumpHosts {
+		if err := r.JumpHosts[i].validate(); err != nil {
+			return errors.New("jump host " + strconv.Itoa(i+1) + ": " + err.Error())
+		}
+	}
+	return validateSize(r.Cols, r.Rows)
+}
+
+// 대상 또는 점프 호스트가 서버 측 인증 정보 (저장된 키, ssh-agent)를 요청하는지 확인
+func (r *connectRequest) usesServerCredentials() bool {
+	hosts := append([]hostRequest{r.hostRequest}, r.JumpHosts...)
+	for _, host := range hosts {
+		if host.KeyRef != "" || host.UseAgent {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *resizeRequest) validate() error {
+	return validateSize(r.Cols, r.Rows)
+}
+
+func (r *openTerminalRequest) validate() error {
+	if r.Channel == "" {
+		return errors.New("channel is required")
+	}
+	return validateSize(r.Cols, r.Rows)
+}
+
+func (r *resumeRequest) validate() error {
+	if r.Token == "" {
+		return errors.New("token is required")
+	}
+	return nil
+}
+
+func (r *getFileListRequest) validate() error {
+	return requirePath("root", r.Root)
+}
+
+func (r *getFileContentsRequest) validate() error {
+	return requirePath("path", r.Path)
+}
+
+func (r *readRangeRequest) validate() error {
+	if err := requirePath("path", r.Path); err != nil {
+		return err
+	}
+	if r.Offset < 0 || r.Length < 0 || r.LastBytes < 0 || r.LastLines < 0 {
+		return errors.New("range must not be negative")
+	}
+	if r.Length > maxRangeLength || r.LastBytes > maxRangeLength {
+		return errors.New("range is too large (max " + strconv.Itoa(maxRangeLength) + " bytes)")
+	}
+	if r.Length == 0 && r.LastBytes == 0 && r.LastLines == 0 {
+		return errors.New("length, lastBytes or lastLines is required")
+	}
+	return nil
+}
+
+func (r *tailFileRequest) validate() error {
+	if err := requirePath("path", r.Path); err != nil {
+		return err
+	}
+	if r.LastLines < 0 {
+		return errors.New("lastLines must not be negative")
+	}
+	if r.Interval == 0 {
+		r.Interval = defaultTailInterval
+	} else if r.Interval < minTailInterval {
+		return errors.New("interval must be at least " + strconv.Itoa(minTailInterval) + "ms")
+	}
+	return nil
+}
+
+func (r *searchFilesRequest) validate() error {
+	if err := requirePath("path", r.Path); err != nil {
+		return err
+	}
+	if err := validatePatterns(r.Name); err != nil {
+		return err
+	}
+	if r.Content != "" {
+		expr := r.Content
+		if r.IgnoreCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return errors.New("invalid content pattern: " + err.Error())
+		}
+		r.content = re
+	}
+	if r.MinSize < 0 || r.MaxSize < 0 {
+		return errors.New("size must not be negative")
+	}
+	if r.MaxDepth < 0 || r.MaxResults < 0 {
+		return errors.New("limits must not be negative")
+	}
+	if r.MaxResults == 0 || r.MaxResults > maxSearchResults {
+		r.MaxResults = maxSearchResults
+	}
+
+	var err error
+	if r.modifiedAfter, err = parseOptionalTime("modifiedAfter", r.ModifiedAfter); err != nil {
+		return err
+	}
+	if r.modifiedBefore, err = parseOptionalTime("modifiedBefore", r.ModifiedBefore); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *downloadDirRequest) validate() error {
+	if err := requirePath("path", r.Path); err != nil {
+		return err
+	}
+	switch sshclient.ArchiveFormat(r.Format) {
+	case "":
+		r.Format = string(sshclient.ArchiveTarGz)
+	case sshclient.ArchiveTarGz, sshclient.ArchiveZip:
+	default:
+		return errors.New("unsupported format: " + r.Format)
+	}
+	if err := validatePatterns(r.Include); err != nil {
+		return err
+	}
+	if err := validatePatterns(r.Exclude); err != nil {
+		return err
+	}
+	if r.MaxSize < 0 {
+		return errors.New("maxSize must not be negative")
+	}
+	if r.MaxSize == 0 || r.MaxSize > maxArchiveSize {
+		r.MaxSize = maxArchiveSize
+	}
+	return nil
+}
+
+func (r *cancelRequest) validate() error {
+	if r.RequestID == "" {
+		return errors.New("requestId is required")
+	}
+	return nil
+}
+
+func (r *saveFileChunkRequest) validate() error {
+	if err := requirePath("path", r.Path); err != nil {
+		return err
+	}
+	content, err := base64.StdEncoding.DecodeString(r.Content)
+	if err != nil {
+		return errors.New("content is not valid base64")
+	}
+	r.content = content
+	return nil
+}
+
+func (r *addFileRequest) validate() error {
+	if err := requirePath("parentPath", r.ParentPath); err != nil {
+		return err
+	}
+	return validateName(r.Filename)
+}
+
+func (r *removeFileRequest) validate() error {
+	return requirePath("fullPath", r.FullPath)
+}
+
+func (r *renameRequest) validate() error {
+	if err := requirePath("path", r.Path); err != nil {
+		return err
+	}
+	return validateName(r.NewName)
+}
+
+func (r *transferRequest) validate() error {
+	if err := requirePaths(r.Paths); err != nil {
+		return err
+	}
+	return requirePath("destination", r.Destination)
+}
+
+func (r *makeDirRequest) validate() error {
+	return requirePath("path", r.Path)
+}
+
+func (r *removeAllRequest) validate() error {
+	return requirePaths(r.Paths)
+}
+
+func (r *chmodRequest) validate() error {
+	if err := requirePath("path", r.Path); err != nil {
+		return err
+	}
+	mode, err := strconv.ParseUint(r.Mode, 8, 32)
+	if err != nil || mode > 07777 {
+		return errors.New("invalid mode: " + r.Mode)
+	}
+	// setuid/setgid/sticky 비트는 os.FileMode 플래그로 변환
+	r.mode = os.FileMode(mode & 0777)
+	if mode&04000 != 0 {
+		r.mode |= os.ModeSetuid
+	}
+	if mode&02000 != 0 {
+		r.mode |= os.ModeSetgid
+	}
+	if mode&01000 != 0 {
+		r.mode |= os.ModeSticky
+	}
+	return nil
+}
+
+func (r *chownRequest) validate() error {
+	if err := requirePath("path", r.Path); err != nil {
+		return err
+	}
+	if r.Owner == "" && r.Group == "" {
+		return errors.New("owner or group is required")
+	}
+	return nil
+}
+
+func (r *uploadStartRequest) validate() error {
+	if err := requirePath("path", r.Path); err != nil {
+		return err
+	}
+	if r.Size < 0 {
+		return errors.New("size must not be negative")
+	}
+	return nil
+}
+
+func (r *uploadChunkRequest) validate() error {
+	if err := requireUploadID(r.UploadID); err != nil {
+		return err
+	}
+	if r.Offset < 0 {
+		return errors.New("offset must not be negative")
+	}
+	content, err := base64.StdEncoding.DecodeString(r.Content)
+	if err != nil {
+		return errors.New("content is not valid base64")
+	}
+	r.content = content
+	return nil
+}
+
+func (r *uploadRequest) validate() error {
+	return requireUploadID(r.UploadID)
+}
+
+func (r *uploadCommitRequest) validate() error {
+	if err := requireUploadID(r.UploadID); err != nil {
+		return err
+	}
+	if r.Checksum == "" {
+		return errors.New("checksum is required")
+	}
+	return nil
+}
+
+func (r *uploadExtractRequest) validate() error {
+	if err := requireUploadID(r.UploadID); err != nil {
+		return err
+	}
+	if r.Checksum == "" {
+		return errors.New("checksum is required")
+	}
+	if err := requirePath("destination", r.Destination); err != nil {
+		return err
+	}
+	switch sshclient.ArchiveFormat(r.Format) {
+	case "", sshclient.ArchiveTarGz, sshclient.ArchiveZip:
+	default:
+		return errors.New("unsupported format: " + r.Format)
+	}
+	switch sshclient.ExtractPolicy(r.Policy) {
+	case "":
+		r.Policy = string(sshclient.ExtractFail)
+	case sshclient.ExtractFail, sshclient.ExtractSkip, sshclient.ExtractOverwrite:
+	default:
+		return errors.New("unsupported policy: " + r.Policy)
+	}
+	return nil
+}
+
+func validateSize(cols, rows int) error {
+	if cols <= 0 || rows <= 0 {
+		return errors.New("cols and rows must be positive")
+	}
+	return nil
+}
+
+func requirePath(name, path string) error {
+	if path == "" {
+		return errors.New(name + " is required")
+	}
+	return nil
+}
+
+func requirePaths(paths []string) error {
+	if len(paths) == 0 {
+		return errors.New("paths is required")
+	}
+	for _, p := range paths {
+		if err := requirePath("path", p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 경로 구분자가 없는 파일 이름인지 확인
+func validateName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		return errors.New("invalid filename: " + name)
+	}
+	return nil
+}
+
+func requireUploadID(id string) error {
+	if id == "" {
+		return errors.New("uploadId is required")
+	}
+	return nil
+}
+
+func parseOptionalTime(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New(name + " must be RFC3339")
+	}
+	return t, nil
+}
+
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.New("invalid pattern: " + pattern)
+		}
+	}
+	return nil
+}
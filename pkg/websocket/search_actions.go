@@ -0,0 +1,65 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"sshbck/pkg/sshclient"
+)
+
+const (
+	maxSearchResults  = 1000     // 검색 결과 최대 개수
+	maxSearchFileSize = 16 << 20 // 내용 검색 대상 파일 크기 제한
+)
+
+// 디렉토리 아래 파일 검색 (결과는 찾는 대로 전송, cancel 액션으로 중지 가능)
+func handleSearchFiles(wsCtx *WSHandlerContext, req searchFilesRequest) error {
+	ctx, done, err := wsCtx.optionalTask()
+	if err != nil {
+		return err
+	}
+
+	wsCtx.goSafe("search files", func() {
+		defer done()
+
+		stats, err := wsCtx.ssh.Search(ctx, req.Path, sshclient.SearchOptions{
+			Name:           req.Name,
+			Content:        req.content,
+			MinSize:        req.MinSize,
+			MaxSize:        req.MaxSize,
+			ModifiedAfter:  req.modifiedAfter,
+			ModifiedBefore: req.modifiedBefore,
+			MaxDepth:       req.MaxDepth,
+			MaxResults:     req.MaxResults,
+			MaxFileSize:    maxSearchFileSize,
+		}, func(match sshclient.SearchMatch) error {
+			return sendSearch(wsCtx, match, StatusInProgress)
+		})
+
+		result := searchResultResponse{SearchStats: stats, Done: true}
+		switch {
+		case errors.Is(err, errDetached):
+			log.Println("search stopped: websocket detached")
+			return
+		case errors.Is(err, context.Canceled):
+			result.Canceled = true
+		case err != nil:
+			log.Println("search error:", err)
+			wsCtx.safeWS.WriteJSON(wsCtx.message(ActionSearchFiles, nil, StatusFailed, "search error: "+err.Error()))
+			return
+		}
+		if err := sendSearch(wsCtx, result, StatusSuccess); err != nil {
+			log.Println("websocket write error:", err)
+		}
+	})
+	return nil
+}
+
+func sendSearch(wsCtx *WSHandlerContext, data interface{}, status Status) error {
+	msg, err := toJSON(data)
+	if err != nil {
+		return errors.New("json marshal error: " + err.Error())
+	}
+	return wsCtx.safeWS.WriteJSON(wsCtx.message(ActionSearchFiles, msg, status, ""))
+}
@@ -0,0 +1,338 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 분리된 동안 터미널 채널별로 보관하는 출력 최대 크기
+const maxDetachedOutput = 1 << 20
+
+var errDetached = errors.New("websocket detached")
+
+// WebSocket 연결과 독립적으로 유지되는 세션 상태
+type bridgeSession struct {
+	mu      sync.Mutex
+	token   string                     // 재연결 토큰 (연결 완료 후 발급)
+	active  bool                       // SSH 연결 중이거나 연결됨 (connection 액션은 한 번만 허용)
+	outputs map[string]*terminalOutput // 채널 ID -> 전송 대기 출력
+	reaper  *time.Timer                // 분리된 세션 정리 타이머
+	tasks   map[string]*task           // 요청 ID -> 취소 가능한 작업
+}
+
+// 요청 ID로 취소할 수 있는 장기 작업 (tailfile 등)
+type task struct {
+	cancel context.CancelFunc
+}
+
+// 분리된 동안 쌓이는 터미널 출력
+type terminalOutput struct {
+	mu      sync.Mutex
+	backlog []byte
+}
+
+// 재연결 가능한 세션 목록 (토큰 -> 세션)
+type sessionRegistry struct {
+	mu       sync.Mutex
+	sessions map[string]*WSHandlerContext
+}
+
+var sessions = &sessionRegistry{sessions: make(map[string]*WSHandlerContext)}
+
+func newBridgeSession() *bridgeSession {
+	return &bridgeSession{
+		outputs: make(map[string]*terminalOutput),
+		tasks:   make(map[string]*task),
+	}
+}
+
+func (r *sessionRegistry) add(token string, wsCtx *WSHandlerContext) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[token] = wsCtx
+}
+
+func (r *sessionRegistry) get(token string) *WSHandlerContext {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sessions[token]
+}
+
+func (r *sessionRegistry) remove(token string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, token)
+}
+
+// 재연결 토큰 발급 및 세션 등록
+func (wsCtx *WSHandlerContext) registerSession() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	wsCtx.session.mu.Lock()
+	wsCtx.session.token = token
+	wsCtx.session.mu.Unlock()
+
+	sessions.add(token, wsCtx.forRequest(""))
+	return token, nil
+}
+
+// SSH 연결 시작 (이미 연결 중이거나 연결되어 있으면 false)
+func (wsCtx *WSHandlerContext) beginConnect() bool {
+	wsCtx.session.mu.Lock()
+	defer wsCtx.session.mu.Unlock()
+	if wsCtx.session.active {
+		return false
+	}
+	wsCtx.session.active = true
+	return true
+}
+
+// 연결에 실패하면 다시 연결할 수 있도록 상태 해제
+func (wsCtx *WSHandlerContext) abortConnect() {
+	wsCtx.session.mu.Lock()
+	wsCtx.session.active = false
+	wsCtx.session.mu.Unlock()
+}
+
+// SSH 연결 중이거나 연결되어 있는지 확인
+func (wsCtx *WSHandlerContext) connectActive() bool {
+	wsCtx.session.mu.Lock()
+	defer wsCtx.session.mu.Unlock()
+	return wsCtx.session.active
+}
+
+// 세션 종료 시 연결 닫기 및 등록 해제
+func (wsCtx *WSHandlerContext) watchSession() {
+	<-wsCtx.ctx.Done()
+
+	wsCtx.session.mu.Lock()
+	token := wsCtx.session.token
+	if wsCtx.session.reaper != nil {
+		wsCtx.session.reaper.Stop()
+	}
+	wsCtx.session.mu.Unlock()
+
+	if token != "" {
+		sessions.remove(token)
+	}
+	wsCtx.safeWS.Close()
+}
+
+// WebSocket 연결 종료 처리 (재연결 가능하면 유예 기간 동안 세션 유지)
+func (wsCtx *WSHandlerContext) disconnect(conn *websocket.Conn) {
+	if !wsCtx.safeWS.detach(conn) {
+		// 이미 다른 연결이 세션을 이어받음
+		return
+	}
+
+	if wsCtx.ctx.Err() != nil {
+		// 셸이 종료되는 등 세션이 이미 끝나 재연결을 기다릴 필요가 없음
+		return
+	}
+
+	wsCtx.session.mu.Lock()
+	defer wsCtx.session.mu.Unlock()
+
+	if wsCtx.session.token == "" || options.ResumeGrace <= 0 {
+		wsCtx.cancel()
+		return
+	}
+
+	log.Println("session detached, waiting for resume:", options.ResumeGrace)
+	var reaper *time.Timer
+	reaper = time.AfterFunc(options.ResumeGrace, func() {
+		wsCtx.session.mu.Lock()
+		defer wsCtx.session.mu.Unlock()
+		if wsCtx.session.reaper != reaper {
+			// 만료 직전에 재연결되어 타이머가 해제됨
+			return
+		}
+		log.Println("detached session expired")
+		wsCtx.cancel()
+	})
+	wsCtx.session.reaper = reaper
+}
+
+// 토큰으로 분리된 세션에 새 연결을 붙임
+func resumeSession(fresh *WSHandlerContext, conn *websocket.Conn, msg WSMessage) (*WSHandlerContext, error) {
+	var req resumeRequest
+	if err := decodeData(msg.Data, &req); err != nil {
+		return nil, err
+	}
+	if fresh.connectActive() {
+		return nil, errors.New("connection already has a session")
+	}
+
+	target := sessions.get(req.Token)
+	if target == nil {
+		return nil, errors.New("session not found or expired")
+	}
+
+	target.session.mu.Lock()
+	if target.ctx.Err() != nil {
+		target.session.mu.Unlock()
+		return nil, errors.New("session not found or expired")
+	}
+	if reaper := target.session.reaper; reaper != nil {
+		if !reaper.Stop() {
+			// 타이머가 이미 실행되어 잠금을 기다리는 중이므로 세션을 종료하도록 둠
+			target.session.mu.Unlock()
+			return nil, errors.New("session not found or expired")
+		}
+		target.session.reaper = nil
+	}
+	target.session.mu.Unlock()
+
+	// 이전 연결이 아직 살아 있으면 닫고 새 연결로 교체
+	if old := target.safeWS.attach(conn); old != nil && old != conn {
+		old.Close()
+	}
+	fresh.safeWS.detach(conn)
+	fresh.cancel()
+
+	reqCtx := target.forRequest(msg.ID)
+	channels := reqCtx.flushOutputs()
+
+	state, err := toJSON(connectionStateResponse{State: ConnStateResumed, Channels: channels})
+	if err != nil {
+		return nil, errors.New("json marshal error: " + err.Error())
+	}
+	reqCtx.safeWS.WriteJSON(reqCtx.message(ActionResume, state, StatusSuccess, ""))
+
+	return target, nil
+}
+
+// 요청 ID로 취소할 수 있는 작업 등록 (작업이 끝나면 반환된 함수 호출)
+func (wsCtx *WSHandlerContext) startTask() (context.Context, func(), error) {
+	id := wsCtx.requestID
+	if id == "" {
+		return nil, nil, errors.New("request id is required for a cancellable task")
+	}
+
+	ctx, cancel := context.WithCancel(wsCtx.ctx)
+	t := &task{cancel: cancel}
+
+	wsCtx.session.mu.Lock()
+	defer wsCtx.session.mu.Unlock()
+	if _, exists := wsCtx.session.tasks[id]; exists {
+		cancel()
+		return nil, nil, errors.New("task already running: " + id)
+	}
+	wsCtx.session.tasks[id] = t
+
+	done := func() {
+		cancel()
+		wsCtx.session.mu.Lock()
+		if wsCtx.session.tasks[id] == t {
+			delete(wsCtx.session.tasks, id)
+		}
+		wsCtx.session.mu.Unlock()
+	}
+	return ctx, done, nil
+}
+
+// 요청 ID가 있으면 취소할 수 있는 작업으로 등록하고, 없으면 세션 컨텍스트에서 실행
+func (wsCtx *WSHandlerContext) optionalTask() (context.Context, func(), error) {
+	if wsCtx.requestID == "" {
+		return wsCtx.ctx, func() {}, nil
+	}
+	return wsCtx.startTask()
+}
+
+// 요청 ID로 작업 취소
+func (wsCtx *WSHandlerContext) cancelTask(id string) bool {
+	wsCtx.session.mu.Lock()
+	t := wsCtx.session.tasks[id]
+	wsCtx.session.mu.Unlock()
+
+	if t == nil {
+		return false
+	}
+	t.cancel()
+	return true
+}
+
+// 채널별 출력 버퍼 생성
+func (wsCtx *WSHandlerContext) newTerminalOutput(channel string) *terminalOutput {
+	out := &terminalOutput{}
+
+	wsCtx.session.mu.Lock()
+	wsCtx.session.outputs[channel] = out
+	wsCtx.session.mu.Unlock()
+	return out
+}
+
+func (wsCtx *WSHandlerContext) removeTerminalOutput(channel string) {
+	wsCtx.session.mu.Lock()
+	delete(wsCtx.session.outputs, channel)
+	wsCtx.session.mu.Unlock()
+}
+
+// 분리된 동안 쌓인 출력을 모두 전송하고 열린 채널 목록 반환
+func (wsCtx *WSHandlerContext) flushOutputs() []string {
+	wsCtx.session.mu.Lock()
+	outputs := make(map[string]*terminalOutput, len(wsCtx.session.outputs))
+	for channel, out := range wsCtx.session.outputs {
+		outputs[channel] = out
+	}
+	wsCtx.session.mu.Unlock()
+
+	channels := make([]string, 0, len(outputs))
+	for channel, out := range outputs {
+		out.mu.Lock()
+		if err := out.flush(wsCtx, channel); err != nil {
+			log.Println("WebSocket write error:", err)
+		}
+		out.mu.Unlock()
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// 터미널 출력 전송 (전송할 수 없으면 backlog에 보관)
+func (out *terminalOutput) send(wsCtx *WSHandlerContext, channel string, frame []byte) {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+
+	if err := out.flush(wsCtx, channel); err == nil {
+		err = wsCtx.safeWS.WriteMessage(websocket.TextMessage, wsCtx.terminalMessage(channel, frame))
+		if err == nil {
+			return
+		}
+	}
+
+	out.backlog = append(out.backlog, frame...)
+	if over := len(out.backlog) - maxDetachedOutput; over > 0 {
+		// 오래된 출력부터 버림
+		out.backlog = append([]byte(nil), out.backlog[over:]...)
+	}
+}
+
+// backlog 전송 (out.mu를 잡은 상태에서 호출)
+func (out *terminalOutput) flush(wsCtx *WSHandlerContext, channel string) error {
+	for len(out.backlog) > 0 {
+		n := len(out.backlog)
+		if n > maxTerminalFrame {
+			n = maxTerminalFrame
+		}
+		if err := wsCtx.safeWS.WriteMessage(websocket.TextMessage, wsCtx.terminalMessage(channel, out.backlog[:n])); err != nil {
+			return err
+		}
+		out.backlog = out.backlog[n:]
+	}
+	out.backlog = nil
+	return nil
+}
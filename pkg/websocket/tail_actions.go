@@ -0,0 +1,88 @@
+package websocket
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"sshbck/pkg/sshclient"
+
+	"github.com/gorilla/websocket"
+)
+
+// tailfile 확인 간격 (밀리초)
+const (
+	defaultTailInterval = 1000
+	minTailInterval     = 200
+)
+
+// 파일에 추가되는 내용을 계속 전송 (cancel 액션에 이 요청의 ID를 지정해 중지)
+func handleTailFile(wsCtx *WSHandlerContext, req tailFileRequest) error {
+	ctx, done, err := wsCtx.startTask()
+	if err != nil {
+		return err
+	}
+
+	wsCtx.goSafe("tail file", func() {
+		defer done()
+
+		chunk := FileChunk{FileHash: generateUniqueHash(req.Path), Path: req.Path}
+		send := func(data []byte, event string) error {
+			chunk.Status = FileStatusInProgress
+			chunk.Content = base64.StdEncoding.EncodeToString(data)
+			chunk.Event = event
+			if err := sendFileChunk(wsCtx, ActionTailFile, chunk, StatusInProgress); err != nil {
+				return err
+			}
+			chunk.Index++
+			return nil
+		}
+
+		// 마지막 몇 줄을 먼저 보내고 그 다음 위치부터 이어서 읽음
+		offset := int64(-1)
+		if req.LastLines > 0 {
+			last, err := wsCtx.ssh.ReadLastLines(req.Path, req.LastLines, maxRangeLength)
+			if err != nil {
+				sendFileChunkError(wsCtx, ActionTailFile, chunk, err)
+				return
+			}
+			if err := send(last.Content, ""); err != nil {
+				log.Println("websocket write error:", err)
+				return
+			}
+			offset = last.Offset + int64(len(last.Content))
+		}
+
+		interval := time.Duration(req.Interval) * time.Millisecond
+		err := wsCtx.ssh.Follow(ctx, req.Path, offset, interval, func(data []byte, event sshclient.FollowEvent) error {
+			return send(data, string(event))
+		})
+		if errors.Is(err, errDetached) {
+			log.Println("tail stopped: websocket detached")
+			return
+		} else if err != nil {
+			log.Println("tail error:", err)
+			sendFileChunkError(wsCtx, ActionTailFile, chunk, err)
+			return
+		}
+
+		chunk.Status = FileStatusSuccess
+		chunk.Content = ""
+		chunk.Event = ""
+		if err := sendFileChunk(wsCtx, ActionTailFile, chunk, StatusSuccess); err != nil {
+			log.Println("websocket write error:", err)
+		}
+	})
+	return nil
+}
+
+// 파일 청크 전송
+func sendFileChunk(wsCtx *WSHandlerContext, action Action, chunk FileChunk, status Status) error {
+	msg, err := json.Marshal(chunk)
+	if err != nil {
+		return errors.New("json marshal error: " + err.Error())
+	}
+	return wsCtx.safeWS.WriteMessage(websocket.TextMessage, wsCtx.message(action, msg, status, ""))
+}
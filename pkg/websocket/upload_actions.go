@@ -0,0 +1,111 @@
+package websocket
+
+import (
+	"errors"
+
+	"sshbck/pkg/sshclient"
+)
+
+// 업로드 시작 (응답의 uploadId로 청크 전송)
+func handleUploadStart(wsCtx *WSHandlerContext, req uploadStartRequest) error {
+	upload, err := wsCtx.ssh.StartUpload(req.Path, req.Size, sshclient.ChecksumAlgorithm(req.Algorithm))
+	if err != nil {
+		return errors.New("upload start error: " + err.Error())
+	}
+	return sendUploadState(wsCtx, ActionUploadStart, upload.ID)
+}
+
+// offset 위치에 청크 쓰기
+func handleUploadChunk(wsCtx *WSHandlerContext, req uploadChunkRequest) error {
+	upload, err := wsCtx.ssh.Upload(req.UploadID)
+	if err != nil {
+		return err
+	}
+	if err := upload.WriteAt(req.Offset, req.content); err != nil {
+		return errors.New("upload chunk error: " + err.Error())
+	}
+	return sendUploadState(wsCtx, ActionUploadChunk, upload.ID)
+}
+
+// 수신한 구간 조회 (재연결 후 이어서 보낼 위치 확인용)
+func handleUploadStatus(wsCtx *WSHandlerContext, req uploadRequest) error {
+	return sendUploadState(wsCtx, ActionUploadStatus, req.UploadID)
+}
+
+// 체크섬 검증 후 최종 경로에 저장
+func handleUploadCommit(wsCtx *WSHandlerContext, req uploadCommitRequest) error {
+	upload, err := wsCtx.ssh.Upload(req.UploadID)
+	if err != nil {
+		return err
+	}
+	version, err := wsCtx.ssh.CommitUpload(req.UploadID, sshclient.CommitOptions{
+		Checksum:    req.Checksum,
+		BaseVersion: req.BaseVersion,
+		Verify:      req.Verify,
+	})
+	if sendConflict(wsCtx, ActionUploadCommit, err) {
+		return nil
+	} else if err != nil {
+		return errors.New("upload commit error: " + err.Error())
+	}
+
+	msg, err := toJSON(savedFileResponse{Path: upload.Path, Version: version})
+	if err != nil {
+		return errors.New("json marshal error: " + err.Error())
+	}
+	wsCtx.safeWS.WriteJSON(wsCtx.message(ActionUploadCommit, msg, StatusSuccess, ""))
+	return nil
+}
+
+// 업로드한 압축 파일을 대상 디렉토리에 해제 (항목마다 진행 상황 전송, cancel 액션으로 중지 가능)
+func handleUploadExtract(wsCtx *WSHandlerContext, req uploadExtractRequest) error {
+	upload, err := wsCtx.ssh.Upload(req.UploadID)
+	if err != nil {
+		return err
+	}
+	ctx, done, err := wsCtx.optionalTask()
+	if err != nil {
+		return err
+	}
+
+	runFileOp(wsCtx, ActionUploadExtract, []string{upload.Path}, func(src string, notify sshclient.ItemFunc) error {
+		defer done()
+		return wsCtx.ssh.ExtractUpload(ctx, req.UploadID, sshclient.ExtractOptions{
+			Checksum:    req.Checksum,
+			Destination: req.Destination,
+			Format:      sshclient.ArchiveFormat(req.Format),
+			Policy:      sshclient.ExtractPolicy(req.Policy),
+			MaxSize:     maxArchiveSize,
+		}, notify)
+	})
+	return nil
+}
+
+// 업로드 취소
+func handleUploadAbort(wsCtx *WSHandlerContext, req uploadRequest) error {
+	if err := wsCtx.ssh.AbortUpload(req.UploadID); err != nil {
+		return errors.New("upload abort error: " + err.Error())
+	}
+	wsCtx.safeWS.WriteJSON(wsCtx.message(ActionUploadAbort, nil, StatusSuccess, ""))
+	return nil
+}
+
+func sendUploadState(wsCtx *WSHandlerContext, action Action, id string) error {
+	upload, err := wsCtx.ssh.Upload(id)
+	if err != nil {
+		return err
+	}
+
+	msg, err := toJSON(uploadResponse{
+		UploadID:  upload.ID,
+		Path:      upload.Path,
+		Size:      upload.Size,
+		Algorithm: string(upload.Algorithm),
+		Ranges:    upload.Ranges(),
+	})
+	if err != nil {
+		return errors.New("json marshal error: " + err.Error())
+	}
+	wsCtx.safeWS.WriteJSON(wsCtx.message(action, msg, StatusSuccess, ""))
+	return nil
+}
@@ -9,7 +9,7 @@ import (
 )
 
 // Json 변환
-func toJSON(data map[string]interface{}) ([]byte, error) {
+func toJSON(data interface{}) ([]byte, error) {
 	return json.Marshal(data)
 }
 
@@ -19,14 +19,52 @@ func generateUniqueHash(filePath string) string {
 	return fmt.Sprintf("%x", hash)
 }
 
-// WebSocket 메시지 생성 함수
-func createMessage(action string, data []byte, status Status, error string) []byte {
-	message, err := json.Marshal(map[string]interface{}{
+// WebSocket 메시지 생성 함수 (id가 있으면 요청 ID로 함께 전송)
+func createMessage(action string, id string, data []byte, status Status, error string) []byte {
+	message := map[string]interface{}{
 		"action": action,
 		"data":   data, // Marshal 함수로 인해, []byte가 base64 인코딩 됨
 		"status": status,
 		"error":  error,
-	})
+	}
+	if id != "" {
+		message["id"] = id
+	}
+	return marshalMessage(message)
+}
+
+// 오류 코드를 포함한 실패 메시지 생성 함수
+func createErrorMessage(action string, id string, data []byte, code string, error string) []byte {
+	message := map[string]interface{}{
+		"action": action,
+		"data":   data,
+		"status": StatusFailed,
+		"error":  error,
+		"code":   code,
+	}
+	if id != "" {
+		message["id"] = id
+	}
+	return marshalMessage(message)
+}
+
+// 터미널 출력 메시지 생성 함수 (channel로 PTY 채널 구분)
+func createTerminalMessage(id string, channel string, data []byte) []byte {
+	message := map[string]interface{}{
+		"action":  ActionTerminal,
+		"channel": channel,
+		"data":    data,
+		"status":  StatusSuccess,
+		"error":   "",
+	}
+	if id != "" {
+		message["id"] = id
+	}
+	return marshalMessage(message)
+}
+
+func marshalMessage(data map[string]interface{}) []byte {
+	message, err := json.Marshal(data)
 	if err != nil {
 		log.Println("JSON marshal error:", err)
 		return nil
@@ -2,11 +2,16 @@ package websocket
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/base64"
+	"encoding/json"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 
+	"sshbck/pkg/recorder"
 	"sshbck/pkg/sshclient"
 
 	"github.com/gorilla/websocket"
@@ -23,6 +28,41 @@ const (
 	ActionSaveFileChunk   Action = "savefilechunk"
 	ActionAddFile         Action = "addfile"
 	ActionRemoveFile      Action = "removefile"
+	ActionRename          Action = "rename"
+	ActionMove            Action = "move"
+	ActionCopy            Action = "copy"
+	ActionMakeDir         Action = "mkdir"
+	ActionRemoveAll       Action = "removeall"
+	ActionChmod           Action = "chmod"
+	ActionChown           Action = "chown"
+	ActionUploadStart     Action = "uploadstart"
+	ActionUploadChunk     Action = "uploadchunk"
+	ActionUploadStatus    Action = "uploadstatus"
+	ActionUploadCommit    Action = "uploadcommit"
+	ActionUploadAbort     Action = "uploadabort"
+	ActionUploadExtract   Action = "uploadextract"
+	ActionReadRange       Action = "readrange"
+	ActionTailFile        Action = "tailfile"
+	ActionCancel          Action = "cancel"
+	ActionDownloadDir     Action = "downloaddir"
+	ActionSearchFiles     Action = "searchfiles"
+
+	ActionKeyboardInteractive Action = "keyboardinteractive"
+	ActionHostKey             Action = "hostkey"
+	ActionOpenTerminal        Action = "openterminal"
+	ActionCloseTerminal       Action = "closeterminal"
+	ActionResume              Action = "resume"
+)
+
+// 연결 상태 (ActionConnect 메시지의 state)
+const (
+	ConnStateDialing        = string(sshclient.StageDialing)
+	ConnStateAuthenticating = string(sshclient.StageAuthenticating)
+	ConnStatePTYReady       = "pty-ready"
+	ConnStateSFTPReady      = "sftp-ready"
+	ConnStateFailed         = "failed"
+	ConnStateClosed         = "closed"
+	ConnStateResumed        = "resumed"
 )
 
 // 타입 정의
@@ -33,10 +73,12 @@ type (
 
 type (
 	WSMessage struct {
-		Action Action                 `json:"action"`
-		Data   map[string]interface{} `json:"data"`
-		Status Status                 `json:"status"`
-		Error  string                 `json:"error,omitempty"`
+		ID     string          `json:"id,omitempty"` // 응답에 그대로 전달되는 요청 ID
+		Action Action          `json:"action"`
+		Data   json.RawMessage `json:"data"`
+		Status Status          `json:"status"`
+		Error  string          `json:"error,omitempty"`
+		Code   string          `json:"code,omitempty"`
 	}
 
 	WSError struct {
@@ -54,32 +96,65 @@ type (
 		ctx    context.Context
 		ssh    *sshclient.SSHContext
 		safeWS *SafeWebSocket
-		done   chan struct{}
 		cancel context.CancelFunc
+
+		session   *bridgeSession
+		replies   *pendingReplies
+		requestID string // 처리 중인 요청의 ID (forRequest로 설정)
+
+		serverCredentials bool // 서버 측 인증 정보 (keyRef, useAgent) 사용 허용
 	}
 )
 
+// 브릿지 설정
+type Options struct {
+	KeyStore      sshclient.KeyStore      // 서버 측 개인키 저장소
+	AgentSocket   string                  // ssh-agent 소켓 경로
+	KnownHosts    *sshclient.KnownHosts   // 호스트 키 검증용 known_hosts
+	HostKeyPolicy sshclient.HostKeyPolicy // 기본 호스트 키 정책
+	ResumeGrace   time.Duration           // WebSocket 연결이 끊긴 뒤 세션을 유지하는 시간 (0이면 즉시 종료)
+	Recording     recorder.Config         // PTY 세션 녹화 설정 (Dir이 비어있으면 녹화하지 않음)
+
+	// KeyStore, AgentSocket 사용에 필요한 토큰 ("Authorization: Bearer <token>" 으로 확인, 비어 있으면 사용 불가)
+	CredentialToken string
+}
+
+var options Options
+
+// 브릿지 설정 적용
+func Configure(opts Options) {
+	options = opts
+}
+
 const (
 	StatusSuccess    Status = "success"
 	StatusFailed     Status = "failed"
 	StatusInProgress Status = "in-progress"
+	StatusSkipped    Status = "skipped" // 여러 항목 작업에서 건너뛴 항목
 )
 
 func newWSHandlerContext(ws *SafeWebSocket) *WSHandlerContext {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &WSHandlerContext{
+	wsCtx := &WSHandlerContext{
 		ctx:    ctx,
 		cancel: cancel,
-		done:   make(chan struct{}),
 		ssh:    sshclient.NewSSHContext(),
 		safeWS: ws,
+
+		session: newBridgeSession(),
+		replies: newPendingReplies(),
 	}
+	go wsCtx.watchSession()
+	return wsCtx
 }
 
 // 기본 메시지 전송
 func (ws *SafeWebSocket) WriteMessage(messageType int, data []byte) error {
 	ws.Mutex.Lock()
 	defer ws.Mutex.Unlock()
+	if ws.Conn == nil {
+		return errDetached
+	}
 	return ws.Conn.WriteMessage(messageType, []byte(base64.StdEncoding.EncodeToString(data)))
 }
 
@@ -87,26 +162,106 @@ func (ws *SafeWebSocket) WriteMessage(messageType int, data []byte) error {
 func (ws *SafeWebSocket) WriteJSON(data []byte) error {
 	ws.Mutex.Lock()
 	defer ws.Mutex.Unlock()
+	if ws.Conn == nil {
+		return errDetached
+	}
 	return ws.Conn.WriteJSON(data)
 }
 
+// 새 연결로 교체하고 이전 연결 반환
+func (ws *SafeWebSocket) attach(conn *websocket.Conn) *websocket.Conn {
+	ws.Mutex.Lock()
+	defer ws.Mutex.Unlock()
+	old := ws.Conn
+	ws.Conn = conn
+	return old
+}
+
+// 현재 연결이 conn이면 분리
+func (ws *SafeWebSocket) detach(conn *websocket.Conn) bool {
+	ws.Mutex.Lock()
+	defer ws.Mutex.Unlock()
+	if ws.Conn != conn {
+		return false
+	}
+	ws.Conn = nil
+	return true
+}
+
+// 현재 연결 닫기
+func (ws *SafeWebSocket) Close() {
+	ws.Mutex.Lock()
+	defer ws.Mutex.Unlock()
+	if ws.Conn != nil {
+		ws.Conn.Close()
+	}
+}
+
 // WebSocket을 통해 오류 메시지 전송
 func (ws *SafeWebSocket) SendError(msg WSMessage) {
-	message := createMessage(string(msg.Action), nil, msg.Status, msg.Error)
+	message := createMessage(string(msg.Action), msg.ID, nil, msg.Status, msg.Error)
+	if msg.Code != "" {
+		message = createErrorMessage(string(msg.Action), msg.ID, nil, msg.Code, msg.Error)
+	}
 	ws.WriteJSON(message)
 }
 
+// 요청 ID가 지정된 핸들러 컨텍스트 (나머지 상태는 공유)
+func (wsCtx *WSHandlerContext) forRequest(id string) *WSHandlerContext {
+	reqCtx := *wsCtx
+	reqCtx.requestID = id
+	return &reqCtx
+}
+
+// 요청 ID를 포함한 메시지 생성
+func (wsCtx *WSHandlerContext) message(action Action, data []byte, status Status, errMsg string) []byte {
+	return createMessage(string(action), wsCtx.requestID, data, status, errMsg)
+}
+
+// PTY 채널 ID를 포함한 터미널 출력 메시지 생성
+func (wsCtx *WSHandlerContext) terminalMessage(channel string, data []byte) []byte {
+	return createTerminalMessage(wsCtx.requestID, channel, data)
+}
+
+// 요청 ID와 오류 코드를 포함한 실패 메시지 생성
+func (wsCtx *WSHandlerContext) errorMessage(action Action, data []byte, code string, errMsg string) []byte {
+	return createErrorMessage(string(action), wsCtx.requestID, data, code, errMsg)
+}
+
 // 메시지 핸들러 맵
 var messageHandlers = map[Action]messageHandler{
-	ActionConnect:         handleConnect,
-	ActionResize:          handleResize,
-	ActionTerminal:        handleTerminal,
-	ActionGetFileContents: handleGetFileContents,
-	ActionSaveFileChunk:   handleSaveFileChunk,
-	ActionGetFileList:     handleGetFileList,
-	ActionGetGroups:       handleGetGroups,
-	ActionAddFile:         handleAddFile,
-	ActionRemoveFile:      handleRemoveFile,
+	ActionConnect:         typed(handleConnect),
+	ActionResize:          typed(handleResize),
+	ActionTerminal:        typed(handleTerminal),
+	ActionGetFileContents: typed(handleGetFileContents),
+	ActionSaveFileChunk:   typed(handleSaveFileChunk),
+	ActionGetFileList:     typed(handleGetFileList),
+	ActionGetGroups:       typed(handleGetGroups),
+	ActionAddFile:         typed(handleAddFile),
+	ActionRemoveFile:      typed(handleRemoveFile),
+	ActionRename:          typed(handleRename),
+	ActionMove:            typed(handleMove),
+	ActionCopy:            typed(handleCopy),
+	ActionMakeDir:         typed(handleMakeDir),
+	ActionRemoveAll:       typed(handleRemoveAll),
+	ActionChmod:           typed(handleChmod),
+	ActionChown:           typed(handleChown),
+	ActionUploadStart:     typed(handleUploadStart),
+	ActionUploadChunk:     typed(handleUploadChunk),
+	ActionUploadStatus:    typed(handleUploadStatus),
+	ActionUploadCommit:    typed(handleUploadCommit),
+	ActionUploadAbort:     typed(handleUploadAbort),
+	ActionUploadExtract:   typed(handleUploadExtract),
+	ActionReadRange:       typed(handleReadRange),
+	ActionTailFile:        typed(handleTailFile),
+	ActionCancel:          typed(handleCancel),
+	ActionDownloadDir:     typed(handleDownloadDir),
+	ActionSearchFiles:     typed(handleSearchFiles),
+
+	ActionKeyboardInteractive: typed(handleKeyboardInteractive),
+	ActionHostKey:             typed(handleHostKey),
+	ActionOpenTerminal:        typed(handleOpenTerminal),
+	ActionCloseTerminal:       typed(handleCloseTerminal),
 }
 
 // 메시지 라우터 설정
@@ -120,21 +275,41 @@ func setupMessageRouter() *messageRouter {
 	return router
 }
 
-func handleMessages(wsCtx *WSHandlerContext, router *messageRouter) {
+func handleMessages(conn *websocket.Conn, wsCtx *WSHandlerContext, router *messageRouter) {
 	defer func() {
 		log.Println("handleMessages done")
-		close(wsCtx.done)
-		wsCtx.cancel()
+		wsCtx.disconnect(conn)
 	}()
 
 	for {
-		var msg WSMessage
-		err := wsCtx.safeWS.Conn.ReadJSON(&msg)
+		_, raw, err := conn.ReadMessage()
 		if err != nil {
 			log.Println("Read error:", err)
 			return
 		}
 
+		// 형식이 잘못된 메시지는 실패 응답 후 무시
+		var msg WSMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			log.Println("Decode error:", err)
+			wsCtx.safeWS.SendError(WSMessage{Status: StatusFailed, Error: "invalid message: " + err.Error()})
+			continue
+		}
+
+		// 재연결 요청이면 이후 메시지는 이어받은 세션에서 처리
+		if msg.Action == ActionResume {
+			resumed, err := resumeSession(wsCtx, conn, msg)
+			if err != nil {
+				log.Println("Resume error:", err)
+				msg.Status = StatusFailed
+				msg.Error = err.Error()
+				wsCtx.safeWS.SendError(msg)
+				continue
+			}
+			wsCtx = resumed
+			continue
+		}
+
 		if err := router.Route(wsCtx, msg); err != nil {
 			log.Println("Route error:", err)
 			msg.Status = StatusFailed
@@ -160,10 +335,21 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	defer conn.Close()
 
 	wsCtx := newWSHandlerContext(&SafeWebSocket{Conn: conn})
+	wsCtx.serverCredentials = authorizeCredentials(r)
 
-	go handleMessages(wsCtx, setupMessageRouter())
-
-	<-wsCtx.done
+	// 원격 셸이 종료되면 세션이 연결을 닫으므로 읽기가 끝남
+	handleMessages(conn, wsCtx, setupMessageRouter())
 
 	log.Println("HandleWebSocket done")
 }
+
+// 서버 측 인증 정보를 사용할 수 있는 요청인지 확인
+//
+// CheckOrigin이 모든 출처를 허용하므로 토큰 없이는 어떤 페이지에서든 운영자의 키와 에이전트로 접속할 수 있다.
+func authorizeCredentials(r *http.Request) bool {
+	if options.CredentialToken == "" {
+		return false
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(options.CredentialToken)) == 1
+}